@@ -1,9 +1,26 @@
-import React, { useState } from 'react'
+import React, { useState, useEffect } from 'react'
 import './OAuthLink.css'
 
 const OAuthLink = ({ user, onLinkSuccess }) => {
   const [isLoading, setIsLoading] = useState(false)
   const [error, setError] = useState('')
+  const [identities, setIdentities] = useState([])
+
+  useEffect(() => {
+    const token = localStorage.getItem('token')
+    if (!token) {
+      return
+    }
+
+    fetch('/api/user/identities', {
+      headers: {
+        'Authorization': `Bearer ${token}`
+      }
+    })
+      .then(response => response.ok ? response.json() : { identities: [] })
+      .then(data => setIdentities(data.identities || []))
+      .catch(() => setIdentities([]))
+  }, [user])
 
   const handleGoogleLink = () => {
     setIsLoading(true)
@@ -36,14 +53,7 @@ const OAuthLink = ({ user, onLinkSuccess }) => {
   }
 
   const isLinked = (provider) => {
-    switch (provider) {
-      case 'google':
-        return user.google_id
-      case 'github':
-        return user.github_id
-      default:
-        return false
-    }
+    return identities.some(identity => identity.provider === provider)
   }
 
   return (
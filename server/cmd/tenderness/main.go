@@ -1,7 +1,38 @@
 package main
 
-import "tenderness/internal/app"
+import (
+	"flag"
+	"log"
+	"os"
+
+	"tenderness/internal/app"
+	"tenderness/internal/configs"
+)
 
 func main() {
-	app.Run()
+	configPath := flag.String("config", "", "path to a YAML or TOML config file (default: $CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "print the resolved config with secrets redacted and exit")
+	flag.Parse()
+
+	// Настройки выводятся до проверки: так видно, откуда взялось неверное значение
+	if *printConfig {
+		config, err := configs.Read(*configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := config.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		if err := config.Validate(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return
+	}
+
+	config, err := configs.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app.Run(config)
 }
@@ -2,12 +2,17 @@ package app
 
 import (
 	"log"
-	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"tenderness/internal/configs"
 	"tenderness/internal/domain/storage"
 	"tenderness/internal/handlers"
+	"tenderness/internal/mailer"
 	"tenderness/internal/middleware"
+	"tenderness/internal/oauth"
+	"tenderness/internal/payments"
 	"tenderness/internal/repository"
 	"tenderness/internal/routes"
 	"tenderness/internal/services"
@@ -17,42 +22,134 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
-func Run() {
-	config := configs.LoadConfig()
-
-	db, err := storage.NewDatabase(config.GetDSN())
+func Run(config *configs.Config) {
+	db, err := storage.NewDatabase(config.GetDSN(), storage.PoolOptions{
+		MaxOpenConns:    config.DB.MaxOpenConns,
+		MaxIdleConns:    config.DB.MaxIdleConns,
+		ConnMaxLifetime: config.DB.ConnMaxLifetime,
+		ConnMaxIdleTime: config.DB.ConnMaxIdleTime,
+	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	migrationsDir := "migrations"
-	if _, err := os.Stat(migrationsDir); os.IsNotExist(err) {
-		migrationsDir = "/root/migrations"
-	}
-	if err := db.RunMigrations(migrationsDir); err != nil {
+	if err := db.RunMigrations(config.DB.MigrationsDir); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
 	// Product repositories and services
+	txManager := repository.NewTxManager(db.DB)
 	productRepo := repository.NewProductRepository(db.DB)
 	categoryRepo := repository.NewCategoryRepository(db.DB)
-	productService := services.NewProductService(productRepo, categoryRepo)
+	productService := services.NewProductService(txManager, productRepo, categoryRepo)
 
 	// Auth repositories and services
 	userRepo := repository.NewUserRepository(db.DB)
-	jwtMiddleware := middleware.NewJWTMiddleware("your-secret-key-here") // TODO: Move to config
-	authService := services.NewAuthService(userRepo, jwtMiddleware)
-	oauth2Service := services.NewOAuth2Service(userRepo, jwtMiddleware)
+	sessionRepo := repository.NewSessionRepository(db.DB)
+	revocationService := services.NewRevocationService(repository.NewTokenRevocationRepository(db.DB), sessionRepo, config.JWT.AccessTokenTTL)
+	jwtMiddleware := newJWTMiddleware(config, revocationService)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db.DB)
+	sessionService := services.NewSessionService(sessionRepo, refreshTokenRepo, revocationService, config.JWT.AccessTokenTTL)
+	tokenService := services.NewTokenService(txManager, refreshTokenRepo, userRepo, revocationService, sessionService, jwtMiddleware, config.JWT.RefreshTokenTTL)
+	appMailer := newMailer(config)
+	emailVerificationService := services.NewEmailVerificationService(userRepo, appMailer, config.Auth.EmailVerificationSecret, config.AppBaseURL, config.Auth.EmailVerificationTTL, config.Features.EmailVerification)
+	var loginAttemptStore services.LoginAttemptStore = repository.NewLoginAttemptRepository(db.DB)
+	if config.Storage.LoginAttempts == "memory" {
+		loginAttemptStore = services.NewMemoryLoginAttemptStore()
+	}
+	loginThrottle := services.NewLoginThrottleService(loginAttemptStore, userRepo, config.Login.MaxAccountFailures, config.Login.MaxIPFailures, config.Login.LockoutBase, config.Login.LockoutMax, config.Login.AttemptWindow)
+	mfaService := services.NewMFAService(txManager, repository.NewMFARepository(db.DB), userRepo, tokenService, revocationService, jwtMiddleware, loginThrottle, config.Auth.MFAIssuer, config.Auth.MFAChallengeTTL)
+	authService := services.NewAuthService(userRepo, tokenService, emailVerificationService, mfaService, loginThrottle)
+	passwordResetService := services.NewPasswordResetService(txManager, repository.NewPasswordResetRepository(db.DB), userRepo, tokenService, appMailer, config.AppBaseURL, config.Auth.PasswordResetTTL)
+	go deleteExpiredTokens(tokenService, passwordResetService, loginThrottle)
+	oauth2Service := services.NewOAuth2Service(txManager, userRepo, repository.NewUserIdentityRepository(db.DB), tokenService, emailVerificationService, mfaService, newOAuthProviders(config), config.OAuth.StateSecret, config.OAuth.StateTTL, config.OAuth.LinkPolicy, config.OAuth.LinkTTL)
+	roleRepo := repository.NewRoleRepository(db.DB)
+	roleService := services.NewRoleService(roleRepo, userRepo, tokenService)
+	permissionsByRole, err := roleRepo.GetPermissionsByRole()
+	if err != nil {
+		log.Fatalf("Failed to load role permissions: %v", err)
+	}
+	roleMiddleware := middleware.NewRoleMiddleware(jwtMiddleware, permissionsByRole)
+
+	// Cart repositories and services
+	cartRepo := repository.NewCartRepository(db.DB)
+	cartService := services.NewCartService(cartRepo, productRepo, config.Shop.CartSecret)
+
+	// Order repositories and services
+	orderRepo := repository.NewOrderRepository(db.DB)
+	inventoryRepo := repository.NewInventoryRepository(db.DB)
+	orderService := services.NewOrderService(txManager, orderRepo, cartRepo, productRepo, inventoryRepo, config.Shop.ReservationTTL)
+	go releaseExpiredReservations(orderService)
+
+	// Payments
+	paymentRepo := repository.NewPaymentRepository(db.DB)
+	var paymentProviders []payments.PaymentProvider
+	if config.Payments.FakeEnabled {
+		log.Println("Warning: fake payment provider is enabled, payments are not charged")
+		paymentProviders = append(paymentProviders, payments.NewFakeProvider(config.Payments.FakeWebhookSecret, strconv.FormatInt(time.Now().Unix(), 36)))
+	}
+	if config.Payments.Provider == "" {
+		log.Println("Warning: payment provider is not configured, orders cannot be paid")
+	}
+	paymentService := services.NewPaymentService(txManager, paymentRepo, orderService, config.Payments.Provider, config.Payments.Currency, paymentProviders...)
+	go retryRefunds(paymentService)
+
+	// Reviews
+	reviewRepo := repository.NewReviewRepository(db.DB)
+	reviewService := services.NewReviewService(txManager, reviewRepo, productRepo, orderRepo, config.Features.ReviewsRequirePurchase)
+
+	// Wishlist and account data export
+	wishlistRepo := repository.NewWishlistRepository(db.DB)
+	wishlistService := services.NewWishlistService(wishlistRepo, productRepo, cartService)
+	accountService := services.NewAccountService(authService, orderRepo, reviewRepo, wishlistRepo)
 
 	// Handlers
+	cookies := handlers.Cookies{Secure: *config.HTTP.SecureCookies}
 	healthHandler := handlers.NewHealthHandler()
+	jwksHandler := handlers.NewJWKSHandler(jwtMiddleware)
 	productHandler := handlers.NewProductHandler(productService)
-	authHandler := handlers.NewAuthHandler(authService, jwtMiddleware)
-	oauth2Handler := handlers.NewOAuth2Handler(oauth2Service)
+	authHandler := handlers.NewAuthHandler(authService, tokenService, cartService, jwtMiddleware, cookies)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService)
+	emailVerificationHandler := handlers.NewEmailVerificationHandler(emailVerificationService, jwtMiddleware)
+	mfaHandler := handlers.NewMFAHandler(mfaService, cartService, jwtMiddleware, cookies)
+	oauth2Handler := handlers.NewOAuth2Handler(oauth2Service, cartService, handlers.OAuthRedirects{
+		Success: config.OAuth.SuccessURL,
+		MFA:     config.OAuth.MFAURL,
+		Link:    config.OAuth.LinkURL,
+	}, cookies)
+	cartHandler := handlers.NewCartHandler(cartService, jwtMiddleware, cookies)
+	orderHandler := handlers.NewOrderHandler(orderService, jwtMiddleware)
+	paymentHandler := handlers.NewPaymentHandler(paymentService, jwtMiddleware)
+	reviewHandler := handlers.NewReviewHandler(reviewService, jwtMiddleware)
+	wishlistHandler := handlers.NewWishlistHandler(wishlistService, jwtMiddleware)
+	accountHandler := handlers.NewAccountHandler(accountService, jwtMiddleware)
+	sessionHandler := handlers.NewSessionHandler(sessionService, jwtMiddleware)
+	adminHandler := handlers.NewAdminHandler(productService, orderService, paymentService, roleService, loginThrottle, jwtMiddleware)
+
+	// Rate limiting
+	var rateLimitStore middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if config.Storage.RateLimits == "postgres" {
+		rateLimitStore = repository.NewRateLimitRepository(db.DB)
+	}
+	rateLimitPolicies := map[string]middleware.RateLimit{
+		middleware.RateLimitGlobal:  rateLimit(config.RateLimit.Global),
+		middleware.RateLimitAuth:    rateLimit(config.RateLimit.Auth),
+		middleware.RateLimitCatalog: rateLimit(config.RateLimit.Catalog),
+		middleware.RateLimitUser:    rateLimit(config.RateLimit.User),
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore, jwtMiddleware, rateLimitPolicies)
+	go deleteStaleRateLimits(rateLimitStore, rateLimitPolicies)
 
 	app := fiber.New(fiber.Config{
 		AppName: "Tenderness App",
+		// За прокси c.IP() берётся из ProxyHeader, но только для запросов от TrustedProxies
+		ProxyHeader:             config.HTTP.ProxyHeader,
+		EnableTrustedProxyCheck: len(config.HTTP.TrustedProxies) > 0,
+		TrustedProxies:          config.HTTP.TrustedProxies,
+		ReadTimeout:             config.HTTP.ReadTimeout,
+		WriteTimeout:            config.HTTP.WriteTimeout,
+		IdleTimeout:             config.HTTP.IdleTimeout,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -66,13 +163,153 @@ func Run() {
 
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
+		AllowOrigins:     strings.Join(config.CORS.AllowOrigins, ","),
+		AllowMethods:     strings.Join(config.CORS.AllowMethods, ","),
+		AllowHeaders:     strings.Join(config.CORS.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(config.CORS.ExposeHeaders, ","),
+		AllowCredentials: config.CORS.AllowCredentials,
+		MaxAge:           int(config.CORS.MaxAge.Seconds()),
 	}))
+	// Пользователь определяется до общего лимита, чтобы вошедшие считались по ID, а не по IP
+	app.Use(jwtMiddleware.OptionalJWTAuth(), rateLimiter.Limit(middleware.RateLimitGlobal))
+
+	routes.SetupRoutes(app, healthHandler, jwksHandler, productHandler, authHandler, passwordResetHandler, emailVerificationHandler, mfaHandler, oauth2Handler, cartHandler, orderHandler, paymentHandler, reviewHandler, wishlistHandler, accountHandler, sessionHandler, adminHandler, jwtMiddleware, roleMiddleware, rateLimiter)
+
+	log.Println("Starting server on port " + config.HTTP.Port)
+	log.Fatal(app.Listen(config.HTTP.Port))
+}
+
+// releaseExpiredReservations периодически отменяет неоплаченные заказы с истёкшим резервом товара
+func releaseExpiredReservations(orderService *services.OrderService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cancelled, err := orderService.ExpireReservations()
+		if err != nil {
+			log.Printf("Failed to release expired reservations: %v", err)
+			continue
+		}
+		if cancelled > 0 {
+			log.Printf("Cancelled %d orders with expired stock reservations", cancelled)
+		}
+	}
+}
+
+// retryRefunds периодически повторяет возвраты, которые не удалось завершить сразу
+func retryRefunds(paymentService *services.PaymentService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		refunded, err := paymentService.RetryRefunds()
+		if err != nil {
+			log.Printf("Failed to retry pending refunds: %v", err)
+			continue
+		}
+		if refunded > 0 {
+			log.Printf("Completed %d pending refunds", refunded)
+		}
+	}
+}
 
-	routes.SetupRoutes(app, healthHandler, productHandler, authHandler, oauth2Handler, jwtMiddleware)
+// deleteExpiredTokens периодически удаляет истёкшие refresh токены, записи об отзыве,
+// токены сброса пароля и устаревшие счётчики неудачных входов
+func deleteExpiredTokens(tokenService *services.TokenService, passwordResetService *services.PasswordResetService, loginThrottle *services.LoginThrottleService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
 
-	log.Println("Starting server on port " + config.Port)
-	log.Fatal(app.Listen(config.Port))
+	for range ticker.C {
+		if err := tokenService.DeleteExpired(); err != nil {
+			log.Printf("Failed to delete expired tokens: %v", err)
+		}
+		if err := passwordResetService.DeleteExpired(); err != nil {
+			log.Printf("Failed to delete expired password reset tokens: %v", err)
+		}
+		if err := loginThrottle.DeleteStale(); err != nil {
+			log.Printf("Failed to delete stale login attempts: %v", err)
+		}
+	}
+}
+
+// newMailer выбирает способ отправки писем по конфигурации
+func newMailer(config *configs.Config) mailer.Mailer {
+	if config.Mail.Driver == "smtp" {
+		return mailer.NewSMTPMailer(config.Mail.SMTPHost, config.Mail.SMTPPort, config.Mail.SMTPUsername, config.Mail.SMTPPassword, config.Mail.From)
+	}
+	return mailer.NewLogMailer(config.Mail.OutputDir, config.Mail.From)
+}
+
+// newJWTMiddleware загружает ключи access токенов из конфигурации
+func newJWTMiddleware(config *configs.Config, revocations middleware.RevocationChecker) *middleware.JWTMiddleware {
+	var (
+		signingKey *middleware.JWTKey
+		err        error
+	)
+	if config.JWT.SigningKey != "" {
+		signingKey, err = middleware.ParseSigningKey(config.JWT.SigningKey)
+	} else {
+		// Без ключа конфигурация проходит проверку только с jwt.allow_ephemeral_key
+		log.Println("Warning: JWT_SIGNING_KEY is not set, using a temporary key: tokens will not survive a restart and are not accepted by other instances")
+		signingKey, err = middleware.GenerateSigningKey()
+	}
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing key: %v", err)
+	}
+
+	verificationKeys, err := middleware.ParseVerificationKeys(config.JWT.VerificationKeys...)
+	if err != nil {
+		log.Fatalf("Failed to load JWT verification keys: %v", err)
+	}
+
+	return middleware.NewJWTMiddleware(signingKey, verificationKeys, config.JWT.AccessTokenTTL, revocations)
+}
+
+// newOAuthProviders создаёт провайдеров входа по конфигурации
+func newOAuthProviders(config *configs.Config) *oauth.Registry {
+	providers := make([]oauth.OAuthProvider, 0, len(config.OAuth.Providers))
+	for _, provider := range config.OAuth.Providers {
+		providerConfig := oauth.Config{
+			Name:         provider.Name,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		}
+
+		switch provider.Type {
+		case "oidc":
+			providers = append(providers, oauth.NewOIDCProvider(providerConfig, provider.Issuer))
+		case "github":
+			providers = append(providers, oauth.NewGitHubProvider(providerConfig))
+		default:
+			log.Fatalf("OAuth provider %q has unknown type %q", provider.Name, provider.Type)
+		}
+	}
+	return oauth.NewRegistry(providers...)
+}
+
+func rateLimit(limit configs.RateLimit) middleware.RateLimit {
+	return middleware.RateLimit{
+		Requests: limit.Requests,
+		Per:      limit.Per,
+	}
+}
+
+// deleteStaleRateLimits периодически удаляет корзины, которые успели полностью
+// пополниться: их удаление ничего не меняет
+func deleteStaleRateLimits(store middleware.RateLimitStore, policies map[string]middleware.RateLimit) {
+	longest := time.Minute
+	for _, policy := range policies {
+		longest = max(longest, policy.Per)
+	}
+
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := store.DeleteStale(time.Now().Add(-longest)); err != nil {
+			log.Printf("Failed to delete stale rate limit buckets: %v", err)
+		}
+	}
 }
@@ -2,53 +2,318 @@ package configs
 
 import (
 	"fmt"
-	"log"
-	"os"
-
-	"github.com/joho/godotenv"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Config - настройки приложения. Значения берутся по умолчанию, затем из файла
+// (YAML или TOML) и переменных окружения: переменная из тега env важнее файла.
+// Поля с тегом secret скрываются в --print-config.
 type Config struct {
-	Port      string
-	DBHost    string
-	DBPort    string
-	DBUser    string
-	DBPass    string
-	DBName    string
-	DBSSLMode string
-}
-
-func LoadConfig() *Config {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Warning: .env file not found, proceeding with OS environment variables")
+	// Env - окружение: development разрешает секреты из примеров и возможности только для
+	// разработки, production (по умолчанию) их отклоняет
+	Env string `yaml:"env" toml:"env" env:"APP_ENV"`
+
+	// AppBaseURL - адрес фронтенда, на который ведут ссылки из писем и редиректы после входа
+	AppBaseURL string `yaml:"app_base_url" toml:"app_base_url" env:"APP_BASE_URL"`
+
+	HTTP      HTTPConfig      `yaml:"http" toml:"http"`
+	DB        DBConfig        `yaml:"db" toml:"db"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	OAuth     OAuthConfig     `yaml:"oauth" toml:"oauth"`
+	CORS      CORSConfig      `yaml:"cors" toml:"cors"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	Storage   StorageConfig   `yaml:"storage" toml:"storage"`
+	Features  FeaturesConfig  `yaml:"features" toml:"features"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	Login     LoginConfig     `yaml:"login" toml:"login"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Shop      ShopConfig      `yaml:"shop" toml:"shop"`
+	Payments  PaymentsConfig  `yaml:"payments" toml:"payments"`
+
+	// problems - ошибки разбора переменных окружения и файлов ключей, их возвращает Validate
+	problems []string
+}
+
+type HTTPConfig struct {
+	Port string `yaml:"port" toml:"port" env:"PORT"`
+	// Адрес клиента за обратным прокси: заголовок с IP и подсети прокси, которым можно верить
+	ProxyHeader    string   `yaml:"proxy_header" toml:"proxy_header" env:"PROXY_HEADER"`
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// Таймауты соединения; 0 - без ограничения
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// SecureCookies отправляет cookie только по HTTPS; если не задано - везде, кроме development
+	SecureCookies *bool `yaml:"secure_cookies" toml:"secure_cookies" env:"HTTP_SECURE_COOKIES"`
+}
+
+type DBConfig struct {
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port     string `yaml:"port" toml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE"`
+
+	// Пул соединений; 0 в MaxOpenConns - без ограничения
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	MigrationsDir string `yaml:"migrations_dir" toml:"migrations_dir" env:"DB_MIGRATIONS_DIR"`
+}
+
+type JWTConfig struct {
+	// Время жизни access (JWT) и refresh токенов
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+
+	// Ключи access токенов в PEM: SigningKey - закрытый ключ RSA (RS256) или Ed25519 (EdDSA),
+	// которым подписываются новые токены; VerificationKeys - прежние ключи, токены которых
+	// ещё принимаются во время ротации. Ключи можно задать и файлами.
+	SigningKey           string   `yaml:"signing_key" toml:"signing_key" env:"JWT_SIGNING_KEY" secret:"true"`
+	SigningKeyFile       string   `yaml:"signing_key_file" toml:"signing_key_file" env:"JWT_SIGNING_KEY_FILE"`
+	VerificationKeys     []string `yaml:"verification_keys" toml:"verification_keys"`
+	VerificationKeyFiles []string `yaml:"verification_key_files" toml:"verification_key_files" env:"JWT_VERIFICATION_KEY_FILES"`
+	// AllowEphemeralKey разрешает запуск без ключа подписи: при каждом запуске создаётся
+	// временный ключ. Только для разработки.
+	AllowEphemeralKey bool `yaml:"allow_ephemeral_key" toml:"allow_ephemeral_key" env:"JWT_ALLOW_EPHEMERAL_KEY"`
+}
+
+type OAuthConfig struct {
+	// StateSecret подписывает cookie с state и PKCE verifier, StateTTL - срок её жизни
+	StateSecret string        `yaml:"state_secret" toml:"state_secret" env:"OAUTH_STATE_SECRET" secret:"true"`
+	StateTTL    time.Duration `yaml:"state_ttl" toml:"state_ttl" env:"OAUTH_STATE_TTL"`
+
+	// Вход через провайдера с email существующего аккаунта: LinkPolicy = verified
+	// (привязать сразу, если email подтверждён и провайдером, и в аккаунте) | confirm
+	// (всегда после входа в аккаунт), LinkTTL - сколько действует link_token
+	LinkPolicy string        `yaml:"link_policy" toml:"link_policy" env:"OAUTH_LINK_POLICY"`
+	LinkTTL    time.Duration `yaml:"link_ttl" toml:"link_ttl" env:"OAUTH_LINK_TTL"`
+
+	// Страницы фронтенда, на которые callback перенаправляет после входа, при запросе
+	// второго фактора и при необходимости подтвердить привязку. По умолчанию - {AppBaseURL}/auth/...
+	SuccessURL string `yaml:"success_url" toml:"success_url" env:"OAUTH_SUCCESS_URL"`
+	MFAURL     string `yaml:"mfa_url" toml:"mfa_url" env:"OAUTH_MFA_URL"`
+	LinkURL    string `yaml:"link_url" toml:"link_url" env:"OAUTH_LINK_URL"`
+
+	// Providers - провайдеры входа; переменные OAUTH_PROVIDERS и OAUTH_<NAME>_* применяются
+	// поверх файла. Провайдеры без client id пропускаются.
+	Providers []OAuthProvider `yaml:"providers" toml:"providers"`
+}
+
+// OAuthProvider - провайдер входа. Type = oidc (любой провайдер OpenID Connect,
+// адреса берутся из {Issuer}/.well-known/openid-configuration) | github
+type OAuthProvider struct {
+	Name         string   `yaml:"name" toml:"name"`
+	Type         string   `yaml:"type" toml:"type"`
+	Issuer       string   `yaml:"issuer" toml:"issuer"`
+	ClientID     string   `yaml:"client_id" toml:"client_id"`
+	ClientSecret string   `yaml:"client_secret" toml:"client_secret" secret:"true"`
+	RedirectURL  string   `yaml:"redirect_url" toml:"redirect_url"`
+	Scopes       []string `yaml:"scopes" toml:"scopes"`
+}
+
+type CORSConfig struct {
+	AllowOrigins     []string `yaml:"allow_origins" toml:"allow_origins" env:"CORS_ALLOW_ORIGINS"`
+	AllowMethods     []string `yaml:"allow_methods" toml:"allow_methods" env:"CORS_ALLOW_METHODS"`
+	AllowHeaders     []string `yaml:"allow_headers" toml:"allow_headers" env:"CORS_ALLOW_HEADERS"`
+	ExposeHeaders    []string `yaml:"expose_headers" toml:"expose_headers" env:"CORS_EXPOSE_HEADERS"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	// MaxAge - сколько браузер кэширует ответ на preflight запрос
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// MailConfig - почта: Driver=log пишет письма в лог (и в OutputDir), Driver=smtp отправляет через SMTP
+type MailConfig struct {
+	Driver       string `yaml:"driver" toml:"driver" env:"MAILER"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `yaml:"smtp_port" toml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+	From         string `yaml:"from" toml:"from" env:"MAIL_FROM"`
+	OutputDir    string `yaml:"output_dir" toml:"output_dir" env:"MAIL_OUTPUT_DIR"`
+}
+
+// StorageConfig - где хранятся счётчики неудачных входов и лимитов запросов: postgres | memory.
+// memory подходит только для одного экземпляра приложения.
+type StorageConfig struct {
+	LoginAttempts string `yaml:"login_attempts" toml:"login_attempts" env:"LOGIN_ATTEMPT_STORE"`
+	RateLimits    string `yaml:"rate_limits" toml:"rate_limits" env:"RATE_LIMIT_STORE"`
+}
+
+type FeaturesConfig struct {
+	// ReviewsRequirePurchase разрешает отзывы только на товары из доставленных заказов
+	ReviewsRequirePurchase bool `yaml:"reviews_require_purchase" toml:"reviews_require_purchase" env:"REVIEWS_REQUIRE_PURCHASE"`
+	// EmailVerification = off | checkout | login - что запрещено пользователю, пока email не подтверждён
+	EmailVerification string `yaml:"email_verification" toml:"email_verification" env:"EMAIL_VERIFICATION_REQUIRED"`
+}
+
+type AuthConfig struct {
+	// PasswordResetTTL - срок действия ссылки для сброса пароля
+	PasswordResetTTL time.Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl" env:"PASSWORD_RESET_TTL"`
+
+	EmailVerificationSecret string        `yaml:"email_verification_secret" toml:"email_verification_secret" env:"EMAIL_VERIFICATION_SECRET" secret:"true"`
+	EmailVerificationTTL    time.Duration `yaml:"email_verification_ttl" toml:"email_verification_ttl" env:"EMAIL_VERIFICATION_TTL"`
+
+	// MFAIssuer - название сервиса в приложении-аутентификаторе,
+	// MFAChallengeTTL - сколько действует mfa_token между шагами входа
+	MFAIssuer       string        `yaml:"mfa_issuer" toml:"mfa_issuer" env:"MFA_ISSUER"`
+	MFAChallengeTTL time.Duration `yaml:"mfa_challenge_ttl" toml:"mfa_challenge_ttl" env:"MFA_CHALLENGE_TTL"`
+}
+
+// LoginConfig - защита от перебора паролей: пороги неудачных попыток по аккаунту и по IP,
+// длительность блокировки (удваивается с каждой неудачей сверх порога) и окно,
+// после которого счётчик обнуляется
+type LoginConfig struct {
+	MaxAccountFailures int           `yaml:"max_account_failures" toml:"max_account_failures" env:"LOGIN_MAX_ACCOUNT_FAILURES"`
+	MaxIPFailures      int           `yaml:"max_ip_failures" toml:"max_ip_failures" env:"LOGIN_MAX_IP_FAILURES"`
+	LockoutBase        time.Duration `yaml:"lockout_base" toml:"lockout_base" env:"LOGIN_LOCKOUT_BASE"`
+	LockoutMax         time.Duration `yaml:"lockout_max" toml:"lockout_max" env:"LOGIN_LOCKOUT_MAX"`
+	AttemptWindow      time.Duration `yaml:"attempt_window" toml:"attempt_window" env:"LOGIN_ATTEMPT_WINDOW"`
+}
+
+// RateLimitConfig - лимиты задаются в виде "<запросов>/<период>", например "10/1m", или "off"
+type RateLimitConfig struct {
+	Global  RateLimit `yaml:"global" toml:"global" env:"RATE_LIMIT_GLOBAL"`
+	Auth    RateLimit `yaml:"auth" toml:"auth" env:"RATE_LIMIT_AUTH"`
+	Catalog RateLimit `yaml:"catalog" toml:"catalog" env:"RATE_LIMIT_CATALOG"`
+	User    RateLimit `yaml:"user" toml:"user" env:"RATE_LIMIT_USER"`
+}
+
+type ShopConfig struct {
+	// CartSecret подписывает токены гостевых корзин
+	CartSecret string `yaml:"cart_secret" toml:"cart_secret" env:"CART_SECRET" secret:"true"`
+	// ReservationTTL - сколько товар удерживается под неоплаченный заказ
+	ReservationTTL time.Duration `yaml:"reservation_ttl" toml:"reservation_ttl" env:"RESERVATION_TTL"`
+}
+
+type PaymentsConfig struct {
+	// Provider - провайдер новых платежей; пустое значение отключает оплату
+	Provider string `yaml:"provider" toml:"provider" env:"PAYMENT_PROVIDER"`
+	Currency string `yaml:"currency" toml:"currency" env:"PAYMENT_CURRENCY"`
+	// FakeEnabled подключает провайдер fake, который ничего не списывает. Только для разработки и тестов.
+	FakeEnabled       bool   `yaml:"fake_enabled" toml:"fake_enabled" env:"FAKE_PAYMENTS_ENABLED"`
+	FakeWebhookSecret string `yaml:"fake_webhook_secret" toml:"fake_webhook_secret" env:"FAKE_PAYMENT_WEBHOOK_SECRET" secret:"true"`
+}
+
+// RateLimit - Requests запросов за Per; Requests = 0 отключает ограничение
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// UnmarshalText читает лимит в виде "<запросов>/<период>" или "off"
+func (r *RateLimit) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+	if value == "off" {
+		*r = RateLimit{}
+		return nil
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = ":3000"
+	requests, period, ok := strings.Cut(value, "/")
+	parsedRequests, err := strconv.Atoi(requests)
+	parsedPeriod, periodErr := time.ParseDuration(period)
+	if !ok || err != nil || periodErr != nil || parsedRequests < 0 || parsedPeriod <= 0 {
+		return fmt.Errorf("invalid rate limit %q, expected \"<requests>/<period>\" or \"off\"", value)
 	}
 
+	*r = RateLimit{Requests: parsedRequests, Per: parsedPeriod}
+	return nil
+}
+
+func (r RateLimit) MarshalText() ([]byte, error) {
+	if r.Requests == 0 {
+		return []byte("off"), nil
+	}
+	return []byte(fmt.Sprintf("%d/%s", r.Requests, r.Per)), nil
+}
+
+// Default возвращает настройки по умолчанию, рассчитанные на локальный запуск
+func Default() *Config {
 	return &Config{
-		Port:      port,
-		DBHost:    getEnv("DB_HOST", "localhost"),
-		DBPort:    getEnv("DB_PORT", "5432"),
-		DBUser:    getEnv("DB_USER", "tenderness"),
-		DBPass:    getEnv("DB_PASSWORD", "tenderness123"),
-		DBName:    getEnv("DB_NAME", "tenderness_db"),
-		DBSSLMode: getEnv("DB_SSLMODE", "disable"),
+		Env:        "production",
+		AppBaseURL: "http://localhost",
+		HTTP: HTTPConfig{
+			Port: ":3000",
+		},
+		DB: DBConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			User:            "tenderness",
+			Password:        "tenderness123",
+			Name:            "tenderness_db",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			MigrationsDir:   "migrations",
+		},
+		JWT: JWTConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		OAuth: OAuthConfig{
+			StateSecret: "change-me-oauth-state-secret",
+			StateTTL:    10 * time.Minute,
+			LinkPolicy:  "verified",
+			LinkTTL:     15 * time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowOrigins:  []string{"*"},
+			AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization"},
+			ExposeHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
+		},
+		Mail: MailConfig{
+			Driver:   "log",
+			SMTPHost: "localhost",
+			SMTPPort: "587",
+			From:     "Tenderness <no-reply@tenderness.local>",
+		},
+		Storage: StorageConfig{
+			LoginAttempts: "postgres",
+			RateLimits:    "memory",
+		},
+		Features: FeaturesConfig{
+			EmailVerification: "off",
+		},
+		Auth: AuthConfig{
+			PasswordResetTTL:        time.Hour,
+			EmailVerificationSecret: "change-me-email-verification-secret",
+			EmailVerificationTTL:    48 * time.Hour,
+			MFAIssuer:               "Tenderness",
+			MFAChallengeTTL:         5 * time.Minute,
+		},
+		Login: LoginConfig{
+			MaxAccountFailures: 5,
+			MaxIPFailures:      20,
+			LockoutBase:        time.Minute,
+			LockoutMax:         time.Hour,
+			AttemptWindow:      time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Global:  RateLimit{Requests: 600, Per: time.Minute},
+			Auth:    RateLimit{Requests: 20, Per: time.Minute},
+			Catalog: RateLimit{Requests: 300, Per: time.Minute},
+			User:    RateLimit{Requests: 120, Per: time.Minute},
+		},
+		Shop: ShopConfig{
+			CartSecret:     "change-me-guest-cart-secret",
+			ReservationTTL: 30 * time.Minute,
+		},
+		Payments: PaymentsConfig{
+			Currency:          "RUB",
+			FakeWebhookSecret: "change-me-fake-webhook-secret",
+		},
 	}
 }
 
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		c.DBHost, c.DBUser, c.DBPass, c.DBName, c.DBPort, c.DBSSLMode)
-}
-
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
+		c.DB.Host, c.DB.User, c.DB.Password, c.DB.Name, c.DB.Port, c.DB.SSLMode)
 }
@@ -0,0 +1,310 @@
+package configs
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load собирает настройки: значения по умолчанию, затем файл path (или из CONFIG_FILE),
+// затем переменные окружения и .env. Возвращает все найденные ошибки сразу.
+func Load(path string) (*Config, error) {
+	config, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Read собирает настройки так же, как Load, но не проверяет их: ошибки разбора
+// переменных окружения и файлов ключей возвращает потом Validate. Нужен, чтобы
+// --print-config показывал и неверную конфигурацию.
+func Read(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, proceeding with OS environment variables")
+	}
+
+	config := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(path, config); err != nil {
+			return nil, err
+		}
+	}
+
+	applyEnv(reflect.ValueOf(config).Elem(), &config.problems)
+	if value := os.Getenv("JWT_VERIFICATION_KEYS"); value != "" {
+		config.JWT.VerificationKeys = append(config.JWT.VerificationKeys, value)
+	}
+	config.OAuth.Providers = applyOAuthEnv(config.OAuth.Providers)
+
+	config.problems = append(config.problems, config.resolve()...)
+	return config, nil
+}
+
+// Validate проверяет настройки и возвращает *ValidationError со всеми ошибками,
+// включая ошибки разбора из Read
+func (c *Config) Validate() error {
+	problems := append(slices.Clone(c.problems), c.validate()...)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ValidationError перечисляет все ошибки конфигурации
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// loadFile читает YAML (.yaml, .yml) или TOML (.toml). Неизвестные ключи - ошибка,
+// чтобы опечатка в имени настройки не оставалась незамеченной.
+func loadFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), config)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("failed to parse %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("unsupported config file %s: expected .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv применяет переменные окружения из тегов env. Пустая переменная считается незаданной.
+func applyEnv(v reflect.Value, problems *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+
+		key := field.Tag.Get("env")
+		if key == "" {
+			if field.Type.Kind() == reflect.Struct {
+				applyEnv(value, problems)
+			}
+			continue
+		}
+
+		raw := os.Getenv(key)
+		if raw == "" {
+			continue
+		}
+		if err := setValue(value, raw); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+}
+
+func setValue(value reflect.Value, raw string) error {
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(raw))
+	}
+
+	switch {
+	case value.Type() == durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		value.SetInt(int64(duration))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(int64(parsed))
+	case value.Kind() == reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(parsed)
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		value.Set(reflect.ValueOf(splitList(raw)))
+	case value.Kind() == reflect.Pointer:
+		parsed := reflect.New(value.Type().Elem())
+		if err := setValue(parsed.Elem(), raw); err != nil {
+			return err
+		}
+		value.Set(parsed)
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+// applyOAuthEnv применяет OAUTH_PROVIDERS и OAUTH_<NAME>_* поверх провайдеров из файла.
+// OAUTH_PROVIDERS задаёт список провайдеров; без файла и переменной - google и github.
+// Для client id и secret также поддерживаются прежние переменные <NAME>_CLIENT_ID и <NAME>_CLIENT_SECRET.
+func applyOAuthEnv(providers []OAuthProvider) []OAuthProvider {
+	names := splitList(os.Getenv("OAUTH_PROVIDERS"))
+	if len(names) == 0 && len(providers) == 0 {
+		names = []string{"google", "github"}
+	}
+
+	if len(names) > 0 {
+		selected := make([]OAuthProvider, 0, len(names))
+		for _, name := range names {
+			provider := OAuthProvider{Name: strings.ToLower(name)}
+			for _, configured := range providers {
+				if strings.EqualFold(configured.Name, name) {
+					provider = configured
+				}
+			}
+			selected = append(selected, provider)
+		}
+		providers = selected
+	}
+
+	for i := range providers {
+		provider := &providers[i]
+		provider.Name = strings.ToLower(provider.Name)
+		prefix := strings.ToUpper(provider.Name) + "_"
+
+		overrideEnv(&provider.Type, "OAUTH_"+prefix+"TYPE")
+		overrideEnv(&provider.Issuer, "OAUTH_"+prefix+"ISSUER")
+		overrideEnv(&provider.ClientID, prefix+"CLIENT_ID", "OAUTH_"+prefix+"CLIENT_ID")
+		overrideEnv(&provider.ClientSecret, prefix+"CLIENT_SECRET", "OAUTH_"+prefix+"CLIENT_SECRET")
+		overrideEnv(&provider.RedirectURL, "OAUTH_"+prefix+"REDIRECT_URL")
+		if scopes := splitList(os.Getenv("OAUTH_" + prefix + "SCOPES")); len(scopes) > 0 {
+			provider.Scopes = scopes
+		}
+	}
+	return providers
+}
+
+// overrideEnv записывает в dst значение непустой переменной; из нескольких ключей важнее последний
+func overrideEnv(dst *string, keys ...string) {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			*dst = value
+		}
+	}
+}
+
+// resolve заполняет значения, зависящие от других настроек, и читает файлы ключей
+func (c *Config) resolve() []string {
+	var problems []string
+
+	c.AppBaseURL = strings.TrimRight(c.AppBaseURL, "/")
+	if c.HTTP.SecureCookies == nil {
+		secure := c.Env != "development"
+		c.HTTP.SecureCookies = &secure
+	}
+	if c.OAuth.SuccessURL == "" {
+		c.OAuth.SuccessURL = c.AppBaseURL + "/auth/success"
+	}
+	if c.OAuth.MFAURL == "" {
+		c.OAuth.MFAURL = c.AppBaseURL + "/auth/mfa"
+	}
+	if c.OAuth.LinkURL == "" {
+		c.OAuth.LinkURL = c.AppBaseURL + "/auth/link"
+	}
+
+	providers := make([]OAuthProvider, 0, len(c.OAuth.Providers))
+	for _, provider := range c.OAuth.Providers {
+		if provider.Type == "" {
+			provider.Type = "oidc"
+			if provider.Name == "github" {
+				provider.Type = "github"
+			}
+		}
+		if provider.Issuer == "" && provider.Name == "google" {
+			provider.Issuer = "https://accounts.google.com"
+		}
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = c.AppBaseURL + "/api/oauth2/" + provider.Name + "/callback"
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+			if provider.Type == "github" {
+				provider.Scopes = []string{"user:email"}
+			}
+		}
+
+		if provider.ClientID == "" {
+			log.Printf("Warning: OAuth provider %s has no client id, skipping", provider.Name)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	c.OAuth.Providers = providers
+
+	if c.JWT.SigningKeyFile != "" {
+		if c.JWT.SigningKey != "" {
+			problems = append(problems, "jwt: set either signing_key or signing_key_file, not both")
+		} else if data, err := os.ReadFile(c.JWT.SigningKeyFile); err != nil {
+			problems = append(problems, fmt.Sprintf("jwt.signing_key_file: %v", err))
+		} else {
+			c.JWT.SigningKey = string(data)
+		}
+	}
+	for _, path := range c.JWT.VerificationKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("jwt.verification_key_files: %v", err))
+			continue
+		}
+		c.JWT.VerificationKeys = append(c.JWT.VerificationKeys, string(data))
+	}
+
+	return problems
+}
+
+// splitList читает список через запятую
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
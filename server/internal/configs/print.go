@@ -0,0 +1,47 @@
+package configs
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "<redacted>"
+
+// Print выводит итоговые настройки в YAML, заменяя значения с тегом secret
+func (c *Config) Print(w io.Writer) error {
+	copied := *c
+	redact(reflect.ValueOf(&copied).Elem())
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&copied); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// redact скрывает непустые секреты. Срезы копируются, чтобы не изменить исходную конфигурацию.
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+
+		switch {
+		case field.Tag.Get("secret") == "true" && value.Kind() == reflect.String:
+			if value.String() != "" {
+				value.SetString(redacted)
+			}
+		case value.Kind() == reflect.Struct:
+			redact(value)
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+			reflect.Copy(copied, value)
+			for j := 0; j < copied.Len(); j++ {
+				redact(copied.Index(j))
+			}
+			value.Set(copied)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package configs
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// validate проверяет настройки целиком и возвращает все ошибки сразу, чтобы
+// неверную конфигурацию можно было исправить за один запуск
+func (c *Config) validate() []string {
+	v := &validator{}
+
+	v.oneOf("env", c.Env, "development", "production")
+	v.url("app_base_url", c.AppBaseURL)
+
+	v.required("http.port", c.HTTP.Port)
+	v.nonNegative("http.read_timeout", c.HTTP.ReadTimeout)
+	v.nonNegative("http.write_timeout", c.HTTP.WriteTimeout)
+	v.nonNegative("http.idle_timeout", c.HTTP.IdleTimeout)
+
+	v.required("db.host", c.DB.Host)
+	v.required("db.port", c.DB.Port)
+	v.required("db.user", c.DB.User)
+	v.required("db.name", c.DB.Name)
+	v.oneOf("db.sslmode", c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.check(c.DB.MaxOpenConns >= 0, "db.max_open_conns", "must not be negative")
+	v.check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns", "must not be negative")
+	v.check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns", "must not exceed db.max_open_conns")
+	v.nonNegative("db.conn_max_lifetime", c.DB.ConnMaxLifetime)
+	v.nonNegative("db.conn_max_idle_time", c.DB.ConnMaxIdleTime)
+	if info, err := os.Stat(c.DB.MigrationsDir); err != nil || !info.IsDir() {
+		v.add("db.migrations_dir", fmt.Sprintf("directory %q not found", c.DB.MigrationsDir))
+	}
+
+	v.positive("jwt.access_token_ttl", c.JWT.AccessTokenTTL)
+	v.positive("jwt.refresh_token_ttl", c.JWT.RefreshTokenTTL)
+	v.check(c.JWT.AccessTokenTTL < c.JWT.RefreshTokenTTL, "jwt.refresh_token_ttl", "must be longer than jwt.access_token_ttl")
+	if c.JWT.SigningKey != "" {
+		block, _ := pem.Decode([]byte(c.JWT.SigningKey))
+		v.check(block != nil, "jwt.signing_key", "must be a PEM encoded private key")
+	} else {
+		v.check(c.JWT.AllowEphemeralKey, "jwt.signing_key", "is required unless jwt.allow_ephemeral_key is set")
+	}
+	for _, key := range c.JWT.VerificationKeys {
+		block, _ := pem.Decode([]byte(key))
+		v.check(block != nil, "jwt.verification_keys", "must be PEM encoded keys")
+	}
+
+	v.required("oauth.state_secret", c.OAuth.StateSecret)
+	v.positive("oauth.state_ttl", c.OAuth.StateTTL)
+	v.oneOf("oauth.link_policy", c.OAuth.LinkPolicy, "verified", "confirm")
+	v.positive("oauth.link_ttl", c.OAuth.LinkTTL)
+	v.url("oauth.success_url", c.OAuth.SuccessURL)
+	v.url("oauth.mfa_url", c.OAuth.MFAURL)
+	v.url("oauth.link_url", c.OAuth.LinkURL)
+	names := make(map[string]bool, len(c.OAuth.Providers))
+	for i, provider := range c.OAuth.Providers {
+		field := fmt.Sprintf("oauth.providers[%d]", i)
+		if provider.Name != "" {
+			field = "oauth.providers." + provider.Name
+		}
+		v.required(field+".name", provider.Name)
+		v.check(!names[provider.Name], field+".name", "is used by several providers")
+		names[provider.Name] = true
+		v.oneOf(field+".type", provider.Type, "oidc", "github")
+		if provider.Type == "oidc" {
+			v.url(field+".issuer", provider.Issuer)
+		}
+		v.url(field+".redirect_url", provider.RedirectURL)
+	}
+
+	v.check(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins", "must not be empty")
+	v.check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowOrigins, "*"), "cors.allow_credentials", `cannot be used with allow_origins "*"`)
+	v.nonNegative("cors.max_age", c.CORS.MaxAge)
+
+	v.oneOf("mail.driver", c.Mail.Driver, "log", "smtp")
+	if c.Mail.Driver == "smtp" {
+		v.required("mail.smtp_host", c.Mail.SMTPHost)
+		v.required("mail.smtp_port", c.Mail.SMTPPort)
+	}
+	v.required("mail.from", c.Mail.From)
+
+	v.oneOf("storage.login_attempts", c.Storage.LoginAttempts, "postgres", "memory")
+	v.oneOf("storage.rate_limits", c.Storage.RateLimits, "memory", "postgres")
+
+	v.oneOf("features.email_verification", c.Features.EmailVerification, "off", "checkout", "login")
+
+	v.positive("auth.password_reset_ttl", c.Auth.PasswordResetTTL)
+	v.required("auth.email_verification_secret", c.Auth.EmailVerificationSecret)
+	v.positive("auth.email_verification_ttl", c.Auth.EmailVerificationTTL)
+	v.required("auth.mfa_issuer", c.Auth.MFAIssuer)
+	v.positive("auth.mfa_challenge_ttl", c.Auth.MFAChallengeTTL)
+
+	v.check(c.Login.MaxAccountFailures > 0, "login.max_account_failures", "must be positive")
+	v.check(c.Login.MaxIPFailures > 0, "login.max_ip_failures", "must be positive")
+	v.positive("login.lockout_base", c.Login.LockoutBase)
+	v.check(c.Login.LockoutMax >= c.Login.LockoutBase, "login.lockout_max", "must not be shorter than login.lockout_base")
+	v.positive("login.attempt_window", c.Login.AttemptWindow)
+
+	v.required("shop.cart_secret", c.Shop.CartSecret)
+	v.positive("shop.reservation_ttl", c.Shop.ReservationTTL)
+
+	v.oneOf("payments.provider", c.Payments.Provider, "", "fake")
+	v.check(c.Payments.Provider != "fake" || c.Payments.FakeEnabled, "payments.provider", "fake requires payments.fake_enabled")
+	v.required("payments.currency", c.Payments.Currency)
+	if c.Payments.FakeEnabled {
+		v.required("payments.fake_webhook_secret", c.Payments.FakeWebhookSecret)
+	}
+
+	// Пароль и секреты по умолчанию и из примеров известны всем: годятся только для разработки
+	if c.Env != "development" {
+		v.check(c.DB.Password != Default().DB.Password, "db.password", "must not be the default password outside development")
+		v.check(!c.JWT.AllowEphemeralKey, "jwt.allow_ephemeral_key", "is allowed only in development")
+		v.changed("oauth.state_secret", c.OAuth.StateSecret)
+		v.changed("auth.email_verification_secret", c.Auth.EmailVerificationSecret)
+		v.changed("shop.cart_secret", c.Shop.CartSecret)
+		if c.Payments.FakeEnabled {
+			v.changed("payments.fake_webhook_secret", c.Payments.FakeWebhookSecret)
+		}
+	}
+
+	return v.problems
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(field, problem string) {
+	v.problems = append(v.problems, field+": "+problem)
+}
+
+func (v *validator) check(ok bool, field, problem string) {
+	if !ok {
+		v.add(field, problem)
+	}
+}
+
+func (v *validator) required(field, value string) {
+	v.check(strings.TrimSpace(value) != "", field, "must be set")
+}
+
+func (v *validator) positive(field string, value time.Duration) {
+	v.check(value > 0, field, "must be a positive duration")
+}
+
+func (v *validator) nonNegative(field string, value time.Duration) {
+	v.check(value >= 0, field, "must not be negative")
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	v.check(slices.Contains(allowed, value), field, fmt.Sprintf("must be one of %s, got %q", strings.Join(allowed, ", "), value))
+}
+
+// changed проверяет, что секрет заменён: значения change-me-* взяты из настроек по умолчанию и примеров
+func (v *validator) changed(field, value string) {
+	v.check(!strings.HasPrefix(value, "change-me"), field, "must be changed from the example value outside development")
+}
+
+// url проверяет абсолютный http(s) адрес
+func (v *validator) url(field, value string) {
+	parsed, err := url.Parse(value)
+	v.check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "", field, fmt.Sprintf("must be an absolute http(s) URL, got %q", value))
+}
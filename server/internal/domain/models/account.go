@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// AccountExport - все данные пользователя, которые магазин хранит о нём
+type AccountExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Profile    UserResponse   `json:"profile"`
+	Orders     []Order        `json:"orders"`
+	Reviews    []Review       `json:"reviews"`
+	Wishlist   []WishlistLine `json:"wishlist"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+const MaxCartItemQuantity = 99
+
+type Cart struct {
+	ID        int       `db:"id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	UserID    int       `db:"user_id" json:"user_id,omitempty"`
+	// GuestToken - идентификатор анонимной корзины, для корзин пользователей пустой
+	GuestToken string `db:"guest_token" json:"-"`
+}
+
+// CartOwner определяет владельца корзины: пользователя или анонимного посетителя
+type CartOwner struct {
+	UserID  int
+	GuestID string
+}
+
+func (o CartOwner) IsGuest() bool {
+	return o.UserID == 0
+}
+
+type CartItem struct {
+	ID        int       `db:"id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	CartID    int       `db:"cart_id" json:"cart_id"`
+	ProductID int       `db:"product_id" json:"product_id"`
+	VariantID *int      `db:"variant_id" json:"variant_id,omitempty"`
+	Quantity  int       `db:"quantity" json:"quantity"`
+	UnitPrice float64   `db:"unit_price" json:"unit_price"`
+}
+
+// CartLine - позиция корзины вместе с актуальными данными товара
+type CartLine struct {
+	ID          int     `db:"id" json:"id"`
+	ProductID   int     `db:"product_id" json:"product_id"`
+	ProductName string  `db:"product_name" json:"product_name"`
+	VariantID   *int    `db:"variant_id" json:"variant_id,omitempty"`
+	SKU         string  `db:"sku" json:"sku,omitempty"`
+	VariantName string  `db:"variant_name" json:"variant_name,omitempty"`
+	ImageURL    string  `db:"image_url" json:"image_url"`
+	Quantity    int     `db:"quantity" json:"quantity"`
+	UnitPrice   float64 `db:"unit_price" json:"unit_price"`
+	InStock     bool    `db:"in_stock" json:"in_stock"`
+	// PriceChanged - цена товара изменилась с момента последнего изменения позиции
+	PriceChanged bool    `db:"price_changed" json:"price_changed"`
+	LineTotal    float64 `db:"-" json:"line_total"`
+}
+
+type CartResponse struct {
+	ID         int        `json:"id"`
+	Items      []CartLine `json:"items"`
+	TotalItems int        `json:"total_items"`
+	Total      float64    `json:"total"`
+}
+
+type AddCartItemRequest struct {
+	ProductID int  `json:"product_id" validate:"required,gt=0"`
+	VariantID *int `json:"variant_id" validate:"omitempty,gt=0"`
+	Quantity  int  `json:"quantity" validate:"required,min=1,max=99"`
+}
+
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" validate:"required,min=1,max=99"`
+}
+
+// CartMergeResult описывает результат переноса гостевой корзины в корзину пользователя
+type CartMergeResult struct {
+	Merged  int             `json:"merged"`
+	Skipped []CartMergeSkip `json:"skipped,omitempty"`
+}
+
+type CartMergeSkip struct {
+	ProductID   int    `json:"product_id"`
+	VariantID   *int   `json:"variant_id,omitempty"`
+	ProductName string `json:"product_name"`
+	Reason      string `json:"reason"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type ReservationStatus string
+
+const (
+	// ReservationActive - товар удерживается под неоплаченный заказ
+	ReservationActive ReservationStatus = "active"
+	// ReservationCommitted - заказ оплачен, товар списан со склада
+	ReservationCommitted ReservationStatus = "committed"
+	// ReservationReleased - резерв снят без списания (отмена или истечение срока)
+	ReservationReleased ReservationStatus = "released"
+	// ReservationRestocked - списанный товар возвращён на склад
+	ReservationRestocked ReservationStatus = "restocked"
+)
+
+type StockReservation struct {
+	ID        int               `db:"id" json:"id"`
+	CreatedAt time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time         `db:"updated_at" json:"updated_at"`
+	OrderID   int               `db:"order_id" json:"order_id"`
+	ProductID int               `db:"product_id" json:"product_id"`
+	VariantID *int              `db:"variant_id" json:"variant_id,omitempty"`
+	Quantity  int               `db:"quantity" json:"quantity"`
+	Status    ReservationStatus `db:"status" json:"status"`
+	ExpiresAt time.Time         `db:"expires_at" json:"expires_at"`
+}
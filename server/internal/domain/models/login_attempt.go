@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginAttempt - счётчик неудачных попыток входа для аккаунта или IP адреса
+type LoginAttempt struct {
+	Key           string     `db:"key" json:"key"`
+	Failures      int        `db:"failures" json:"failures"`
+	LastFailureAt time.Time  `db:"last_failure_at" json:"last_failure_at"`
+	LockedUntil   *time.Time `db:"locked_until" json:"locked_until,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// UserTOTP - TOTP секрет пользователя. Пока EnabledAt пуст, секрет ожидает подтверждения.
+type UserTOTP struct {
+	UserID       int        `db:"user_id" json:"-"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	Secret       string     `db:"secret" json:"-"`
+	EnabledAt    *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+	LastUsedStep int64      `db:"last_used_step" json:"-"`
+}
+
+type MFAStatus struct {
+	TOTPEnabled        bool       `json:"totp_enabled"`
+	EnabledAt          *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesCount int        `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollment - данные для добавления аккаунта в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAChallenge возвращается вместо токенов, если для входа нужен второй фактор
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// MFACodeRequest - код из приложения или код восстановления
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
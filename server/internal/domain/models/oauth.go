@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// OAuthFlow - параметры начатого входа через провайдера. Хранится в подписанной
+// cookie и сверяется в callback: state защищает от login CSRF, CodeVerifier - PKCE,
+// Nonce проверяется в id_token провайдеров OpenID Connect.
+type OAuthFlow struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce,omitempty"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+// UserIdentity - аккаунт провайдера входа, привязанный к пользователю. Email, Name
+// и AvatarURL - снимок профиля у провайдера, обновляется при каждом входе.
+type UserIdentity struct {
+	ID            int       `db:"id" json:"-"`
+	UserID        int       `db:"user_id" json:"-"`
+	Provider      string    `db:"provider" json:"provider"`
+	Subject       string    `db:"subject" json:"-"`
+	Email         string    `db:"email" json:"email,omitempty"`
+	EmailVerified bool      `db:"email_verified" json:"email_verified"`
+	Name          string    `db:"name" json:"name,omitempty"`
+	AvatarURL     string    `db:"avatar_url" json:"avatar_url,omitempty"`
+	LinkedAt      time.Time `db:"linked_at" json:"linked_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"-"`
+}
+
+// LinkedAccounts - способы входа, которые есть у аккаунта
+type LinkedAccounts struct {
+	HasPassword bool           `json:"has_password"`
+	Identities  []UserIdentity `json:"identities"`
+}
+
+// PendingOAuthLink - аккаунт провайдера, который ждёт подтверждения привязки
+// к существующему аккаунту с тем же email. Хранится в браузере подписанным link_token.
+type PendingOAuthLink struct {
+	UserID        int    `json:"user_id"`
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+	AvatarURL     string `json:"avatar_url,omitempty"`
+	ExpiresAt     int64  `json:"expires_at"`
+}
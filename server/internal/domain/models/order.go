@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusPacked    OrderStatus = "packed"
+	OrderStatusShipped   OrderStatus = "shipped"
+	OrderStatusDelivered OrderStatus = "delivered"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions описывает допустимые переходы между статусами заказа.
+// cancelled и refunded - конечные состояния.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusPacked, OrderStatusRefunded},
+	OrderStatusPacked:    {OrderStatusShipped, OrderStatusRefunded},
+	OrderStatusShipped:   {OrderStatusDelivered},
+	OrderStatusDelivered: {OrderStatusRefunded},
+}
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusPaid, OrderStatusPacked, OrderStatusShipped,
+		OrderStatusDelivered, OrderStatusCancelled, OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type Order struct {
+	ID              int         `db:"id" json:"id"`
+	CreatedAt       time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time   `db:"updated_at" json:"updated_at"`
+	UserID          int         `db:"user_id" json:"user_id"`
+	Status          OrderStatus `db:"status" json:"status"`
+	Total           float64     `db:"total" json:"total"`
+	ShippingAddress string      `db:"shipping_address" json:"shipping_address"`
+	Comment         string      `db:"comment" json:"comment"`
+
+	Items   []OrderItem         `db:"-" json:"items,omitempty"`
+	History []OrderStatusChange `db:"-" json:"history,omitempty"`
+}
+
+// OrderItem хранит снимок названия и цены товара на момент покупки
+type OrderItem struct {
+	ID          int     `db:"id" json:"id"`
+	OrderID     int     `db:"order_id" json:"order_id"`
+	ProductID   *int    `db:"product_id" json:"product_id"`
+	VariantID   *int    `db:"variant_id" json:"variant_id,omitempty"`
+	ProductName string  `db:"product_name" json:"product_name"`
+	SKU         string  `db:"sku" json:"sku,omitempty"`
+	VariantName string  `db:"variant_name" json:"variant_name,omitempty"`
+	UnitPrice   float64 `db:"unit_price" json:"unit_price"`
+	Quantity    int     `db:"quantity" json:"quantity"`
+	LineTotal   float64 `db:"line_total" json:"line_total"`
+}
+
+type OrderStatusChange struct {
+	ID         int         `db:"id" json:"id"`
+	CreatedAt  time.Time   `db:"created_at" json:"created_at"`
+	OrderID    int         `db:"order_id" json:"order_id"`
+	FromStatus OrderStatus `db:"from_status" json:"from_status"`
+	ToStatus   OrderStatus `db:"to_status" json:"to_status"`
+	ChangedBy  *int        `db:"changed_by" json:"changed_by,omitempty"`
+	Note       string      `db:"note" json:"note"`
+}
+
+type PlaceOrderRequest struct {
+	ShippingAddress string `json:"shipping_address" validate:"required,min=10,max=500"`
+	Comment         string `json:"comment" validate:"max=500"`
+}
+
+// UpdateOrderStatusRequest - ручная смена статуса сотрудником. Оплата и возврат
+// проходят через платёжную систему, поэтому здесь их выставить нельзя.
+type UpdateOrderStatusRequest struct {
+	Status OrderStatus `json:"status" validate:"required,oneof=packed shipped delivered cancelled"`
+	Note   string      `json:"note" validate:"max=500"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusSucceeded PaymentStatus = "succeeded"
+	PaymentStatusFailed    PaymentStatus = "failed"
+	// PaymentStatusRefundPending - возврат начат, но провайдер ещё не подтвердил его.
+	// Такие платежи повторно отправляются на возврат, пока он не пройдёт.
+	PaymentStatusRefundPending PaymentStatus = "refund_pending"
+	PaymentStatusRefunded      PaymentStatus = "refunded"
+)
+
+// Payment - платёж по заказу. Amount хранится в минимальных единицах валюты (копейках).
+type Payment struct {
+	ID                int           `db:"id" json:"id"`
+	CreatedAt         time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time     `db:"updated_at" json:"updated_at"`
+	OrderID           int           `db:"order_id" json:"order_id"`
+	Provider          string        `db:"provider" json:"provider"`
+	ProviderPaymentID string        `db:"provider_payment_id" json:"provider_payment_id"`
+	Status            PaymentStatus `db:"status" json:"status"`
+	Amount            int64         `db:"amount" json:"amount"`
+	Currency          string        `db:"currency" json:"currency"`
+	FailureReason     string        `db:"failure_reason" json:"failure_reason,omitempty"`
+}
+
+type PaymentResponse struct {
+	Payment      Payment `json:"payment"`
+	ClientSecret string  `json:"client_secret,omitempty"`
+}
+
+type ConfirmPaymentRequest struct {
+	PaymentMethod string `json:"payment_method" validate:"required"`
+}
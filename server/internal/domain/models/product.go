@@ -1,7 +1,10 @@
 package models
 
 import (
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Product struct {
@@ -15,14 +18,99 @@ type Product struct {
 	Category    string    `db:"category" json:"category"`
 	InStock     bool      `db:"in_stock" json:"in_stock"`
 	Rating      float64   `db:"rating" json:"rating"`
+	ReviewCount int       `db:"review_count" json:"review_count"`
 	Views       int       `db:"views" json:"views"`
+
+	// Складской остаток; in_stock вычисляется в БД как stock_quantity > reserved_quantity
+	StockQuantity    int `db:"stock_quantity" json:"stock_quantity"`
+	ReservedQuantity int `db:"reserved_quantity" json:"-"`
+
+	// DeletedAt заполнен у товаров, скрытых администратором
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+
+	Variants []ProductVariant `db:"-" json:"variants,omitempty"`
+}
+
+func (p *Product) AvailableQuantity() int {
+	return p.StockQuantity - p.ReservedQuantity
+}
+
+// ProductVariant - вариант товара (размер, цвет, материал) со своим артикулом, ценой и остатком.
+// Если у товара есть варианты, остаток товара равен сумме остатков вариантов.
+type ProductVariant struct {
+	ID               int            `db:"id" json:"id"`
+	CreatedAt        time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time      `db:"updated_at" json:"updated_at"`
+	ProductID        int            `db:"product_id" json:"product_id"`
+	SKU              string         `db:"sku" json:"sku"`
+	Size             string         `db:"size" json:"size,omitempty"`
+	Color            string         `db:"color" json:"color,omitempty"`
+	Material         string         `db:"material" json:"material,omitempty"`
+	Price            *float64       `db:"price" json:"price,omitempty"`
+	StockQuantity    int            `db:"stock_quantity" json:"stock_quantity"`
+	ReservedQuantity int            `db:"reserved_quantity" json:"-"`
+	InStock          bool           `db:"in_stock" json:"in_stock"`
+	ImageURLs        pq.StringArray `db:"image_urls" json:"image_urls"`
+}
+
+func (v *ProductVariant) AvailableQuantity() int {
+	return v.StockQuantity - v.ReservedQuantity
+}
+
+// EffectivePrice возвращает цену варианта, а если она не задана - цену товара
+func (v *ProductVariant) EffectivePrice(product *Product) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
+
+// Label возвращает читаемое название варианта, например "M / черный"
+func (v *ProductVariant) Label() string {
+	var parts []string
+	for _, option := range []string{v.Size, v.Color, v.Material} {
+		if option != "" {
+			parts = append(parts, option)
+		}
+	}
+	return strings.Join(parts, " / ")
 }
 
 type Category struct {
-	ID          int       `db:"id" json:"id"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
-	Name        string    `db:"name" json:"name"`
-	Description string    `db:"description" json:"description"`
-	ImageURL    string    `db:"image_url" json:"image_url"`
+	ID          int        `db:"id" json:"id"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	Name        string     `db:"name" json:"name"`
+	Description string     `db:"description" json:"description"`
+	ImageURL    string     `db:"image_url" json:"image_url"`
+	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+// ProductRequest - данные товара для создания и изменения в админке.
+// Для товаров с вариантами остаток задаётся на вариантах, а stock_quantity игнорируется.
+type ProductRequest struct {
+	Name          string  `json:"name" validate:"required,min=2,max=255"`
+	Description   string  `json:"description" validate:"max=5000"`
+	Price         float64 `json:"price" validate:"required,gt=0"`
+	ImageURL      string  `json:"image_url" validate:"omitempty,url,max=500"`
+	Category      string  `json:"category" validate:"required,max=100"`
+	StockQuantity int     `json:"stock_quantity" validate:"min=0"`
+}
+
+// ProductVariantRequest - данные варианта товара для создания и изменения в админке.
+// Пустая цена означает цену товара.
+type ProductVariantRequest struct {
+	SKU           string   `json:"sku" validate:"required,max=64"`
+	Size          string   `json:"size" validate:"max=20"`
+	Color         string   `json:"color" validate:"max=50"`
+	Material      string   `json:"material" validate:"max=100"`
+	Price         *float64 `json:"price" validate:"omitempty,gt=0"`
+	StockQuantity int      `json:"stock_quantity" validate:"min=0"`
+	ImageURLs     []string `json:"image_urls" validate:"max=20,dive,url,max=500"`
+}
+
+type CategoryRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=255"`
+	Description string `json:"description" validate:"max=2000"`
+	ImageURL    string `json:"image_url" validate:"omitempty,url,max=500"`
 }
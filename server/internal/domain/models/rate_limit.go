@@ -0,0 +1,37 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimitBucket - корзина токенов для ограничения частоты запросов по одному ключу
+type RateLimitBucket struct {
+	Key       string    `db:"key" json:"key"`
+	Tokens    float64   `db:"tokens" json:"tokens"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// NewRateLimitBucket создаёт полную корзину
+func NewRateLimitBucket(key string, burst float64, now time.Time) *RateLimitBucket {
+	return &RateLimitBucket{
+		Key:       key,
+		Tokens:    burst,
+		UpdatedAt: now,
+	}
+}
+
+// Take пополняет корзину за прошедшее время со скоростью rate токенов в секунду,
+// но не больше burst, и забирает один токен, если он есть
+func (b *RateLimitBucket) Take(now time.Time, rate, burst float64) bool {
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*rate)
+		b.UpdatedAt = now
+	}
+
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type Review struct {
+	ID        int       `db:"id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ProductID int       `db:"product_id" json:"product_id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	Rating    int       `db:"rating" json:"rating"`
+	Title     string    `db:"title" json:"title"`
+	Body      string    `db:"body" json:"body"`
+
+	// AuthorName заполняется только при выборке отзывов товара
+	AuthorName string `db:"author_name" json:"author_name,omitempty"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Title  string `json:"title" validate:"max=200"`
+	Body   string `json:"body" validate:"max=5000"`
+}
+
+type CreateReviewRequest struct {
+	ProductID int `json:"product_id" validate:"required,gt=0"`
+	ReviewRequest
+}
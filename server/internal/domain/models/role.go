@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	RoleCustomer       = "customer"
+	RoleSupport        = "support"
+	RoleCatalogManager = "catalog_manager"
+	RoleAdmin          = "admin"
+)
+
+const (
+	PermissionCatalogManage = "catalog.manage"
+	PermissionOrdersManage  = "orders.manage"
+	PermissionRolesManage   = "roles.manage"
+	PermissionUsersUnlock   = "users.unlock"
+)
+
+type UserRole struct {
+	UserID    int       `db:"user_id" json:"user_id"`
+	Role      string    `db:"role" json:"role"`
+	GrantedAt time.Time `db:"granted_at" json:"granted_at"`
+	GrantedBy *int      `db:"granted_by" json:"granted_by,omitempty"`
+}
+
+type GrantRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=customer support catalog_manager admin"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ClientInfo - устройство, с которого пришёл запрос на вход
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// Session - вход пользователя с одного устройства. ID совпадает с семейством
+// refresh токенов этого входа и передаётся в access токенах как sid.
+type Session struct {
+	ID         string     `db:"id" json:"id"`
+	UserID     int        `db:"user_id" json:"-"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"-"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	IP         string     `db:"ip" json:"ip"`
+	// Current отмечает сессию, которой выполнен запрос
+	Current bool `db:"-" json:"current"`
+}
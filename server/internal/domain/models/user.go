@@ -12,24 +12,29 @@ type User struct {
 	LastName  string    `db:"last_name" json:"last_name"`
 	Phone     string    `db:"phone" json:"phone"`
 	IsActive  bool      `db:"is_active" json:"is_active"`
+	Roles     []string  `db:"-" json:"roles"`
 
-	// OAuth2 fields
-	GoogleID     string `db:"google_id" json:"google_id,omitempty"`
-	GithubID     string `db:"github_id" json:"github_id,omitempty"`
+	// EmailVerifiedAt - когда пользователь подтвердил email; nil, пока не подтвердил
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+
+	// OAuth2 fields: аккаунты провайдеров хранятся в user_identities,
+	// AuthProvider - основной способ входа: "email" или имя провайдера
 	AvatarURL    string `db:"avatar_url" json:"avatar_url,omitempty"`
-	AuthProvider string `db:"auth_provider" json:"auth_provider,omitempty"` // "email", "google", "github"
+	AuthProvider string `db:"auth_provider" json:"auth_provider,omitempty"`
 }
 
 type UserResponse struct {
-	ID           int       `json:"id"`
-	Email        string    `json:"email"`
-	FirstName    string    `json:"first_name"`
-	LastName     string    `json:"last_name"`
-	Phone        string    `json:"phone"`
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	AvatarURL    string    `json:"avatar_url,omitempty"`
-	AuthProvider string    `json:"auth_provider,omitempty"`
+	ID            int       `json:"id"`
+	Email         string    `json:"email"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Phone         string    `json:"phone"`
+	IsActive      bool      `json:"is_active"`
+	EmailVerified bool      `json:"email_verified"`
+	Roles         []string  `json:"roles"`
+	CreatedAt     time.Time `json:"created_at"`
+	AvatarURL     string    `json:"avatar_url,omitempty"`
+	AuthProvider  string    `json:"auth_provider,omitempty"`
 }
 
 type LoginRequest struct {
@@ -46,18 +51,35 @@ type RegisterRequest struct {
 }
 
 type OAuthRequest struct {
-	Provider string `json:"provider" validate:"required,oneof=google github"`
+	Provider string `json:"provider" validate:"required"`
 	Code     string `json:"code" validate:"required"`
 	State    string `json:"state" validate:"required"`
 }
 
 type OAuthCallbackRequest struct {
-	Provider string `json:"provider" validate:"required,oneof=google github"`
+	Provider string `json:"provider" validate:"required"`
 	Code     string `json:"code" validate:"required"`
 	State    string `json:"state" validate:"required"`
 }
 
+// AuthResponse - результат входа. Token - короткоживущий access токен (JWT),
+// RefreshToken - непрозрачный токен для получения новой пары через /api/auth/refresh.
+// Если для входа нужен подтверждённый email, после регистрации токены не выдаются
+// и выставлен EmailVerificationRequired.
 type AuthResponse struct {
-	User  UserResponse `json:"user"`
-	Token string       `json:"token"`
+	User                      UserResponse     `json:"user"`
+	Token                     string           `json:"token,omitempty"`
+	ExpiresIn                 int              `json:"expires_in,omitempty"`
+	RefreshToken              string           `json:"refresh_token,omitempty"`
+	RefreshExpiresIn          int              `json:"refresh_expires_in,omitempty"`
+	EmailVerificationRequired bool             `json:"email_verification_required,omitempty"`
+	CartMerge                 *CartMergeResult `json:"cart_merge,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
 }
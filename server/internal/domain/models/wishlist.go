@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type WishlistItem struct {
+	ID        int       `db:"id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	ProductID int       `db:"product_id" json:"product_id"`
+	VariantID *int      `db:"variant_id" json:"variant_id,omitempty"`
+}
+
+// WishlistLine - позиция избранного с актуальной ценой и наличием товара
+type WishlistLine struct {
+	ID          int       `db:"id" json:"id"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	ProductID   int       `db:"product_id" json:"product_id"`
+	ProductName string    `db:"product_name" json:"product_name"`
+	VariantID   *int      `db:"variant_id" json:"variant_id,omitempty"`
+	SKU         string    `db:"sku" json:"sku,omitempty"`
+	VariantName string    `db:"variant_name" json:"variant_name,omitempty"`
+	ImageURL    string    `db:"image_url" json:"image_url"`
+	Price       float64   `db:"price" json:"price"`
+	InStock     bool      `db:"in_stock" json:"in_stock"`
+}
+
+type AddWishlistItemRequest struct {
+	ProductID int  `json:"product_id" validate:"required,gt=0"`
+	VariantID *int `json:"variant_id" validate:"omitempty,gt=0"`
+}
+
+type MoveWishlistItemRequest struct {
+	// VariantID нужен, если в избранное добавлен товар с вариантами без выбора варианта
+	VariantID *int `json:"variant_id" validate:"omitempty,gt=0"`
+	Quantity  int  `json:"quantity" validate:"omitempty,min=1,max=99"`
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+	jwt            *middleware.JWTMiddleware
+}
+
+func NewAccountHandler(accountService *services.AccountService, jwt *middleware.JWTMiddleware) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		jwt:            jwt,
+	}
+}
+
+func (h *AccountHandler) Export(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	export, err := h.accountService.Export(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export account data",
+		})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="account-export.json"`)
+	return c.JSON(export)
+}
@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminHandler - эндпоинты для сотрудников: каталог, заказы и роли пользователей.
+// Доступ к каждой группе проверяется правами в routes.
+type AdminHandler struct {
+	productService *services.ProductService
+	orderService   *services.OrderService
+	paymentService *services.PaymentService
+	roleService    *services.RoleService
+	loginThrottle  *services.LoginThrottleService
+	jwt            *middleware.JWTMiddleware
+}
+
+func NewAdminHandler(productService *services.ProductService, orderService *services.OrderService, paymentService *services.PaymentService, roleService *services.RoleService, loginThrottle *services.LoginThrottleService, jwt *middleware.JWTMiddleware) *AdminHandler {
+	return &AdminHandler{
+		productService: productService,
+		orderService:   orderService,
+		paymentService: paymentService,
+		roleService:    roleService,
+		loginThrottle:  loginThrottle,
+		jwt:            jwt,
+	}
+}
+
+func (h *AdminHandler) GetProducts(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+
+	products, total, err := h.productService.GetAllProducts(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch products",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"products": products,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+func (h *AdminHandler) CreateProduct(c *fiber.Ctx) error {
+	var req models.ProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	product, err := h.productService.CreateProduct(&req)
+	if err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(product)
+}
+
+func (h *AdminHandler) UpdateProduct(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	var req models.ProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	product, err := h.productService.UpdateProduct(id, &req)
+	if err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(product)
+}
+
+func (h *AdminHandler) DeleteProduct(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	if err := h.productService.DeleteProduct(id); err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Product deleted successfully",
+	})
+}
+
+func (h *AdminHandler) RestoreProduct(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	if err := h.productService.RestoreProduct(id); err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Product restored successfully",
+	})
+}
+
+func (h *AdminHandler) GetVariants(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	variants, err := h.productService.GetVariants(productID)
+	if err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"variants": variants,
+	})
+}
+
+func (h *AdminHandler) CreateVariant(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	var req models.ProductVariantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	variant, err := h.productService.CreateVariant(productID, &req)
+	if err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(variant)
+}
+
+func (h *AdminHandler) UpdateVariant(c *fiber.Ctx) error {
+	productID, variantID, err := variantParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product or variant ID",
+		})
+	}
+
+	var req models.ProductVariantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	variant, err := h.productService.UpdateVariant(productID, variantID, &req)
+	if err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(variant)
+}
+
+func (h *AdminHandler) DeleteVariant(c *fiber.Ctx) error {
+	productID, variantID, err := variantParams(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product or variant ID",
+		})
+	}
+
+	if err := h.productService.DeleteVariant(productID, variantID); err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Variant deleted successfully",
+	})
+}
+
+func variantParams(c *fiber.Ctx) (int, int, error) {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return 0, 0, err
+	}
+	variantID, err := strconv.Atoi(c.Params("variantId"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return productID, variantID, nil
+}
+
+func (h *AdminHandler) GetCategories(c *fiber.Ctx) error {
+	categories, err := h.productService.GetAllCategories()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch categories",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"categories": categories,
+	})
+}
+
+func (h *AdminHandler) CreateCategory(c *fiber.Ctx) error {
+	var req models.CategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	category, err := h.productService.CreateCategory(&req)
+	if err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+func (h *AdminHandler) UpdateCategory(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID",
+		})
+	}
+
+	var req models.CategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	category, err := h.productService.UpdateCategory(id, &req)
+	if err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(category)
+}
+
+func (h *AdminHandler) DeleteCategory(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID",
+		})
+	}
+
+	if err := h.productService.DeleteCategory(id); err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Category deleted successfully",
+	})
+}
+
+func (h *AdminHandler) RestoreCategory(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID",
+		})
+	}
+
+	if err := h.productService.RestoreCategory(id); err != nil {
+		return catalogError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Category restored successfully",
+	})
+}
+
+func (h *AdminHandler) GetOrder(c *fiber.Ctx) error {
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	order, err := h.orderService.GetOrderByID(orderID)
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	return c.JSON(order)
+}
+
+func (h *AdminHandler) UpdateOrderStatus(c *fiber.Ctx) error {
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	var req models.UpdateOrderStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	order, err := h.orderService.UpdateStatus(h.jwt.GetUserID(c), orderID, &req)
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	return c.JSON(order)
+}
+
+func (h *AdminHandler) RefundOrder(c *fiber.Ctx) error {
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	changedBy := h.jwt.GetUserID(c)
+	payment, err := h.paymentService.RefundOrder(orderID, &changedBy)
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(payment)
+}
+
+func (h *AdminHandler) GetUserRoles(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	roles, err := h.roleService.GetUserRoles(userID)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"roles": roles,
+	})
+}
+
+func (h *AdminHandler) GrantRole(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req models.GrantRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	roles, err := h.roleService.GrantRole(h.jwt.GetUserID(c), userID, &req)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"roles": roles,
+	})
+}
+
+func (h *AdminHandler) RevokeRole(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	roles, err := h.roleService.RevokeRole(h.jwt.GetUserID(c), userID, c.Params("role"))
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"roles": roles,
+	})
+}
+
+// UnlockLogin снимает блокировку входа после неудачных попыток
+func (h *AdminHandler) UnlockLogin(c *fiber.Ctx) error {
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if err := h.loginThrottle.UnlockUser(h.jwt.GetUserID(c), userID); err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Login unlocked successfully",
+	})
+}
+
+func catalogError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrCategoryNotFound),
+		errors.Is(err, services.ErrVariantNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrCategoryExists), errors.Is(err, services.ErrCategoryInUse),
+		errors.Is(err, services.ErrStockBelowReserved), errors.Is(err, services.ErrVariantExists),
+		errors.Is(err, services.ErrVariantInUse), errors.Is(err, services.ErrProductReserved):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case isValidationError(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update catalog",
+		})
+	}
+}
+
+func roleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrRoleNotGranted):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrCannotRevokeOwnRole):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case isValidationError(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update roles",
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+
 	"tenderness/internal/domain/models"
 	"tenderness/internal/middleware"
 	"tenderness/internal/services"
@@ -9,14 +11,29 @@ import (
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
-	jwt         *middleware.JWTMiddleware
+	authService  *services.AuthService
+	tokenService *services.TokenService
+	cartService  *services.CartService
+	jwt          *middleware.JWTMiddleware
+	cookies      Cookies
 }
 
-func NewAuthHandler(authService *services.AuthService, jwt *middleware.JWTMiddleware) *AuthHandler {
+// refreshTokenCookie хранит refresh токен для браузерных клиентов (выставляется после OAuth2 входа)
+const (
+	refreshTokenCookie     = "refresh_token"
+	refreshTokenCookiePath = "/api/auth"
+)
+
+// accessTokenCookie дублирует access токен после OAuth2 входа
+const accessTokenCookie = "token"
+
+func NewAuthHandler(authService *services.AuthService, tokenService *services.TokenService, cartService *services.CartService, jwt *middleware.JWTMiddleware, cookies Cookies) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		jwt:         jwt,
+		authService:  authService,
+		tokenService: tokenService,
+		cartService:  cartService,
+		jwt:          jwt,
+		cookies:      cookies,
 	}
 }
 
@@ -28,13 +45,18 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.authService.Register(&req)
+	response, err := h.authService.Register(&req, clientInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	// Пока email не подтверждён, входа нет - гостевая корзина остаётся до первого входа
+	if !response.EmailVerificationRequired {
+		response.CartMerge = mergeGuestCart(c, h.cartService, h.cookies, response.User.ID)
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
@@ -46,13 +68,66 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.authService.Login(&req)
+	response, challenge, err := h.authService.Login(&req, clientInfo(c))
+	if lockedErr, ok := loginLockedError(c, err); ok {
+		return lockedErr
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
+	// Гостевая корзина переносится после ввода второго фактора
+	if challenge != nil {
+		return c.JSON(challenge)
+	}
+
+	response.CartMerge = mergeGuestCart(c, h.cartService, h.cookies, response.User.ID)
+
+	return c.JSON(response)
+}
+
+// Refresh обменивает refresh токен на новую пару токенов. Токен берётся
+// из тела запроса, а если его там нет - из cookie refresh_token.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshTokenRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request format",
+			})
+		}
+	}
+
+	fromCookie := false
+	if req.RefreshToken == "" {
+		req.RefreshToken = c.Cookies(refreshTokenCookie)
+		fromCookie = req.RefreshToken != ""
+	}
+
+	response, err := h.tokenService.Refresh(req.RefreshToken, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			h.cookies.clearRefreshToken(c)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	if fromCookie {
+		h.cookies.setRefreshToken(c, response)
+	}
+
 	return c.JSON(response)
 }
 
@@ -126,15 +201,15 @@ func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.authService.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+	response, err := h.authService.ChangePassword(userID, req.CurrentPassword, req.NewPassword, clientInfo(c))
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"message": "Password changed successfully",
-	})
+	// Все прежние токены отозваны, клиент продолжает работу с новыми
+	return c.JSON(response)
 }
 
 func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
@@ -156,8 +231,62 @@ func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
 	})
 }
 
+// Logout отзывает access токен из заголовка Authorization и refresh токен
+// из тела запроса или cookie. Без токенов просто очищает cookie.
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req models.RefreshTokenRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request format",
+			})
+		}
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken = c.Cookies(refreshTokenCookie)
+	}
+
+	if err := h.authService.Logout(h.jwt.GetClaims(c), req.RefreshToken); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log out",
+		})
+	}
+
+	h.cookies.clearRefreshToken(c)
+	h.cookies.Clear(c, accessTokenCookie, "/")
+
 	return c.JSON(fiber.Map{
 		"message": "Logged out successfully",
 	})
 }
+
+func (h *AuthHandler) LogoutEverywhere(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.authService.LogoutEverywhere(userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log out",
+		})
+	}
+
+	h.cookies.clearRefreshToken(c)
+	h.cookies.Clear(c, accessTokenCookie, "/")
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out from all devices",
+	})
+}
+
+// setRefreshToken сохраняет refresh токен на срок его жизни (REFRESH_TOKEN_TTL)
+func (k Cookies) setRefreshToken(c *fiber.Ctx, response *models.AuthResponse) {
+	k.Set(c, refreshTokenCookie, response.RefreshToken, refreshTokenCookiePath, response.RefreshExpiresIn)
+}
+
+func (k Cookies) clearRefreshToken(c *fiber.Ctx) {
+	k.Clear(c, refreshTokenCookie, refreshTokenCookiePath)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CartHandler struct {
+	cartService *services.CartService
+	jwt         *middleware.JWTMiddleware
+	cookies     Cookies
+}
+
+const (
+	guestCartCookie = "guest_cart"
+	guestCartHeader = "X-Guest-Cart"
+	guestCartMaxAge = 30 * 24 * 60 * 60 // 30 days
+)
+
+func NewCartHandler(cartService *services.CartService, jwt *middleware.JWTMiddleware, cookies Cookies) *CartHandler {
+	return &CartHandler{
+		cartService: cartService,
+		jwt:         jwt,
+		cookies:     cookies,
+	}
+}
+
+func (h *CartHandler) GetCart(c *fiber.Ctx) error {
+	owner := h.cartOwner(c, false)
+
+	cart, err := h.cartService.GetCart(owner)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch cart",
+		})
+	}
+
+	return c.JSON(cart)
+}
+
+func (h *CartHandler) AddItem(c *fiber.Ctx) error {
+	owner := h.cartOwner(c, true)
+
+	var req models.AddCartItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	cart, err := h.cartService.AddItem(owner, &req)
+	if err != nil {
+		return cartError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(cart)
+}
+
+func (h *CartHandler) UpdateItem(c *fiber.Ctx) error {
+	owner := h.cartOwner(c, false)
+
+	itemID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cart item ID",
+		})
+	}
+
+	var req models.UpdateCartItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	cart, err := h.cartService.UpdateItem(owner, itemID, &req)
+	if err != nil {
+		return cartError(c, err)
+	}
+
+	return c.JSON(cart)
+}
+
+func (h *CartHandler) RemoveItem(c *fiber.Ctx) error {
+	owner := h.cartOwner(c, false)
+
+	itemID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cart item ID",
+		})
+	}
+
+	cart, err := h.cartService.RemoveItem(owner, itemID)
+	if err != nil {
+		return cartError(c, err)
+	}
+
+	return c.JSON(cart)
+}
+
+func (h *CartHandler) Clear(c *fiber.Ctx) error {
+	owner := h.cartOwner(c, false)
+
+	if err := h.cartService.Clear(owner); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to clear cart",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Cart cleared successfully",
+	})
+}
+
+// cartOwner определяет владельца корзины: авторизованного пользователя или гостя.
+// Гостевой токен берётся из cookie или заголовка X-Guest-Cart; при issue=true
+// гостю без корректного токена выдаётся новый.
+func (h *CartHandler) cartOwner(c *fiber.Ctx, issue bool) models.CartOwner {
+	if userID := h.jwt.GetUserID(c); userID != 0 {
+		return models.CartOwner{UserID: userID}
+	}
+
+	if guestID, err := h.cartService.ParseGuestToken(guestCartToken(c)); err == nil {
+		return models.CartOwner{GuestID: guestID}
+	}
+	if !issue {
+		return models.CartOwner{}
+	}
+
+	token, err := h.cartService.NewGuestToken()
+	if err != nil {
+		log.Printf("Failed to issue guest cart token: %v", err)
+		return models.CartOwner{}
+	}
+
+	h.cookies.Set(c, guestCartCookie, token, "/", guestCartMaxAge)
+	c.Set(guestCartHeader, token)
+
+	guestID, _ := h.cartService.ParseGuestToken(token)
+	return models.CartOwner{GuestID: guestID}
+}
+
+func guestCartToken(c *fiber.Ctx) string {
+	if token := c.Cookies(guestCartCookie); token != "" {
+		return token
+	}
+	return c.Get(guestCartHeader)
+}
+
+// mergeGuestCart переносит гостевую корзину в корзину вошедшего пользователя.
+// Ошибки слияния не должны мешать входу, поэтому они только логируются.
+func mergeGuestCart(c *fiber.Ctx, cartService *services.CartService, cookies Cookies, userID int) *models.CartMergeResult {
+	token := guestCartToken(c)
+	if token == "" {
+		return nil
+	}
+
+	cookies.Clear(c, guestCartCookie, "/")
+
+	guestID, err := cartService.ParseGuestToken(token)
+	if err != nil {
+		return nil
+	}
+
+	result, err := cartService.MergeGuestCart(guestID, userID)
+	if err != nil {
+		log.Printf("Failed to merge guest cart into user %d cart: %v", userID, err)
+		return nil
+	}
+
+	return result
+}
+
+func cartError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrVariantNotFound),
+		errors.Is(err, services.ErrCartItemNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidGuestToken), errors.Is(err, services.ErrVariantRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrProductOutOfStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case isValidationError(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update cart",
+		})
+	}
+}
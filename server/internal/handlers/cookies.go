@@ -0,0 +1,27 @@
+package handlers
+
+import "github.com/gofiber/fiber/v2"
+
+// Cookies выставляет cookie ответа. Все cookie приложения - HttpOnly и SameSite=Lax,
+// а Secure задаётся настройкой http.secure_cookies.
+type Cookies struct {
+	Secure bool
+}
+
+// Set сохраняет cookie на maxAge секунд
+func (k Cookies) Set(c *fiber.Ctx, name, value, path string, maxAge int) {
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		HTTPOnly: true,
+		Secure:   k.Secure,
+		SameSite: "lax",
+		MaxAge:   maxAge,
+	})
+}
+
+// Clear удаляет cookie; path должен совпадать с тем, с которым она выставлялась
+func (k Cookies) Clear(c *fiber.Ctx, name, path string) {
+	k.Set(c, name, "", path, -1)
+}
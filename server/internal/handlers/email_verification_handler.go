@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailVerificationHandler struct {
+	verificationService *services.EmailVerificationService
+	jwt                 *middleware.JWTMiddleware
+}
+
+func NewEmailVerificationHandler(verificationService *services.EmailVerificationService, jwt *middleware.JWTMiddleware) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		verificationService: verificationService,
+		jwt:                 jwt,
+	}
+}
+
+func (h *EmailVerificationHandler) Verify(c *fiber.Ctx) error {
+	var req models.VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.verificationService.Verify(&req); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidVerificationToken), isValidationError(err):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to verify email",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified successfully",
+	})
+}
+
+// Resend всегда отвечает одинаково, даже если аккаунта нет или email уже подтверждён
+func (h *EmailVerificationHandler) Resend(c *fiber.Ctx) error {
+	var req models.ResendVerificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.verificationService.Resend(&req); err != nil {
+		if isValidationError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to resend verification email",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the account exists and is not verified yet, a verification link has been sent",
+	})
+}
+
+// RequireVerifiedForCheckout пропускает к оформлению заказа только пользователей
+// с подтверждённым email, если этого требует EMAIL_VERIFICATION_REQUIRED
+func (h *EmailVerificationHandler) RequireVerifiedForCheckout(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.verificationService.CheckCheckout(userID); err != nil {
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check email verification",
+		})
+	}
+
+	return c.Next()
+}
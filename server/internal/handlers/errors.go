@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"tenderness/internal/services"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+func isValidationError(err error) bool {
+	var validationErrors validator.ValidationErrors
+	return errors.As(err, &validationErrors)
+}
+
+// loginLockedError отвечает 429 с Retry-After, если вход временно заблокирован
+func loginLockedError(c *fiber.Ctx, err error) (error, bool) {
+	var locked *services.LoginLockedError
+	if !errors.As(err, &locked) {
+		return nil, false
+	}
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": locked.Error(),
+	}), true
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"tenderness/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSHandler struct {
+	jwt *middleware.JWTMiddleware
+}
+
+func NewJWKSHandler(jwt *middleware.JWTMiddleware) *JWKSHandler {
+	return &JWKSHandler{jwt: jwt}
+}
+
+// Get публикует открытые ключи, которыми другие сервисы проверяют наши access токены
+func (h *JWKSHandler) Get(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{
+		"keys": h.jwt.JWKS(),
+	})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type MFAHandler struct {
+	mfaService  *services.MFAService
+	cartService *services.CartService
+	jwt         *middleware.JWTMiddleware
+	cookies     Cookies
+}
+
+func NewMFAHandler(mfaService *services.MFAService, cartService *services.CartService, jwt *middleware.JWTMiddleware, cookies Cookies) *MFAHandler {
+	return &MFAHandler{
+		mfaService:  mfaService,
+		cartService: cartService,
+		jwt:         jwt,
+		cookies:     cookies,
+	}
+}
+
+// Login - второй шаг входа: обмен mfa_token и кода на пару токенов
+func (h *MFAHandler) Login(c *fiber.Ctx) error {
+	var req models.MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	response, err := h.mfaService.CompleteLogin(&req, clientInfo(c))
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	response.CartMerge = mergeGuestCart(c, h.cartService, h.cookies, response.User.ID)
+
+	return c.JSON(response)
+}
+
+func (h *MFAHandler) GetStatus(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	status, err := h.mfaService.GetStatus(userID)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(status)
+}
+
+func (h *MFAHandler) EnrollTOTP(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	enrollment, err := h.mfaService.Enroll(userID)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(enrollment)
+}
+
+func (h *MFAHandler) ConfirmTOTP(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	codes, err := h.mfaService.Confirm(userID, &req)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(codes)
+}
+
+func (h *MFAHandler) DisableTOTP(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.mfaService.Disable(userID, &req); err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(userID, &req)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.JSON(codes)
+}
+
+func mfaError(c *fiber.Ctx, err error) error {
+	if lockedErr, ok := loginLockedError(c, err); ok {
+		return lockedErr
+	}
+
+	switch {
+	case errors.Is(err, services.ErrInvalidMFAToken), errors.Is(err, services.ErrInvalidMFACode):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnrolled),
+		errors.Is(err, services.ErrMFANotEnabled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case isValidationError(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process two-factor authentication request",
+		})
+	}
+}
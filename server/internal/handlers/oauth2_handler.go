@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"net/url"
+	"time"
+
 	"tenderness/internal/domain/models"
+	"tenderness/internal/oauth"
 	"tenderness/internal/services"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,33 +14,80 @@ import (
 
 type OAuth2Handler struct {
 	oauth2Service *services.OAuth2Service
+	cartService   *services.CartService
+	redirects     OAuthRedirects
+	cookies       Cookies
+}
+
+// OAuthRedirects - страницы фронтенда, на которые callback перенаправляет пользователя
+type OAuthRedirects struct {
+	// Success получает access токен после входа
+	Success string
+	// MFA получает mfa_token, если нужен второй фактор
+	MFA string
+	// Link предлагает войти в аккаунт с тем же email и подтвердить привязку
+	Link string
 }
 
-func NewOAuth2Handler(oauth2Service *services.OAuth2Service) *OAuth2Handler {
+// oauthStateCookie хранит подписанные state, PKCE verifier и nonce между
+// запросом адреса авторизации и callback
+const (
+	oauthStateCookie     = "oauth_state"
+	oauthStateCookiePath = "/api"
+)
+
+// oauthLinkCookie хранит link_token до подтверждения привязки. Токен не
+// передаётся в адресе: подтвердить привязку можно только в браузере, который
+// входил через провайдера.
+const (
+	oauthLinkCookie     = "oauth_link"
+	oauthLinkCookiePath = "/api/user/identities"
+)
+
+func NewOAuth2Handler(oauth2Service *services.OAuth2Service, cartService *services.CartService, redirects OAuthRedirects, cookies Cookies) *OAuth2Handler {
 	return &OAuth2Handler{
 		oauth2Service: oauth2Service,
+		cartService:   cartService,
+		redirects:     redirects,
+		cookies:       cookies,
 	}
 }
 
+// GetProviders возвращает провайдеров, через которых можно войти
+func (h *OAuth2Handler) GetProviders(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"providers": h.oauth2Service.Providers(),
+	})
+}
+
 func (h *OAuth2Handler) GetAuthURL(c *fiber.Ctx) error {
 	provider := c.Params("provider")
-	state, err := h.oauth2Service.GenerateState()
+
+	authURL, flow, err := h.oauth2Service.BeginAuth(provider)
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid provider",
+		})
+	}
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate state",
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "Provider is unavailable",
 		})
 	}
 
-	authURL, err := h.oauth2Service.GetAuthURL(provider, state)
+	cookie, err := h.oauth2Service.EncodeFlow(flow)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid provider",
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate state",
 		})
 	}
 
+	// SameSite=Lax: cookie должна прийти в callback после перенаправления от провайдера
+	h.cookies.Set(c, oauthStateCookie, cookie, oauthStateCookiePath, int(time.Until(time.Unix(flow.ExpiresAt, 0)).Seconds()))
+
 	return c.JSON(fiber.Map{
 		"auth_url": authURL,
-		"state":    state,
+		"state":    flow.State,
 	})
 }
 
@@ -50,24 +102,54 @@ func (h *OAuth2Handler) Callback(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.oauth2Service.ExchangeCode(provider, code, state)
+	flow, err := h.consumeFlow(c, provider, state)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response, challenge, err := h.oauth2Service.ExchangeCode(provider, code, flow, clientInfo(c))
+	// Аккаунт с этим email уже есть: фронтенд предлагает войти в него и подтвердить привязку
+	var linkRequired *services.OAuthLinkRequiredError
+	if errors.As(err, &linkRequired) {
+		h.cookies.Set(c, oauthLinkCookie, linkRequired.LinkToken, oauthLinkCookiePath, int(time.Until(linkRequired.ExpiresAt).Seconds()))
+		query := url.Values{
+			"link_required": {"true"},
+			"provider":      {linkRequired.Provider},
+		}
+		return c.Redirect(h.redirects.Link+"?"+query.Encode(), fiber.StatusTemporaryRedirect)
+	}
+	if errors.Is(err, services.ErrInvalidOAuthState) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) || errors.Is(err, services.ErrAccountDeactivated) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to exchange code: " + err.Error(),
 		})
 	}
 
+	// Второй фактор вводится на фронтенде, токены выдаёт /api/auth/login/mfa
+	if challenge != nil {
+		return c.Redirect(h.redirects.MFA+"?mfa_token="+url.QueryEscape(challenge.MFAToken), fiber.StatusTemporaryRedirect)
+	}
+
+	mergeGuestCart(c, h.cartService, h.cookies, response.User.ID)
+
 	// Set JWT token in cookie
-	c.Cookie(&fiber.Cookie{
-		Name:     "token",
-		Value:    response.Token,
-		HTTPOnly: true,
-		SameSite: "lax",
-		MaxAge:   86400, // 24 hours
-	})
+	h.cookies.Set(c, accessTokenCookie, response.Token, "/", response.ExpiresIn)
+	// Refresh токен не передаётся в URL, только в HttpOnly cookie
+	h.cookies.setRefreshToken(c, response)
 
 	// Redirect to frontend
-	return c.Redirect("http://localhost/auth/success?token="+response.Token, fiber.StatusTemporaryRedirect)
+	return c.Redirect(h.redirects.Success+"?token="+url.QueryEscape(response.Token), fiber.StatusTemporaryRedirect)
 }
 
 func (h *OAuth2Handler) LinkAccount(c *fiber.Ctx) error {
@@ -80,29 +162,105 @@ func (h *OAuth2Handler) LinkAccount(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.oauth2Service.LinkOAuthToAccount(userID, req.Provider, req.Code)
+	flow, err := h.consumeFlow(c, req.Provider, req.State)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to link account: " + err.Error(),
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
+	if err := h.oauth2Service.LinkOAuthToAccount(userID, req.Provider, req.Code, flow); err != nil {
+		return oauth2Error(c, err, "Failed to link account")
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Account linked successfully",
 	})
 }
 
-func (h *OAuth2Handler) UnlinkAccount(c *fiber.Ctx) error {
-	provider := c.Params("provider")
+// ConfirmLink привязывает провайдера по link_token из cookie, выставленной при входе через него
+func (h *OAuth2Handler) ConfirmLink(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
 
-	if provider != "google" && provider != "github" {
+	linkToken := c.Cookies(oauthLinkCookie)
+	h.cookies.Clear(c, oauthLinkCookie, oauthLinkCookiePath)
+	if linkToken == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid provider",
+			"error": services.ErrInvalidLinkToken.Error(),
 		})
 	}
 
-	// TODO: Implement unlink functionality in repository
+	if err := h.oauth2Service.ConfirmLink(userID, linkToken); err != nil {
+		return oauth2Error(c, err, "Failed to link account")
+	}
+
+	accounts, err := h.oauth2Service.GetLinkedAccounts(userID)
+	if err != nil {
+		return oauth2Error(c, err, "Failed to fetch linked accounts")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":      "Account linked successfully",
+		"has_password": accounts.HasPassword,
+		"identities":   accounts.Identities,
+	})
+}
+
+// GetLinkedAccounts возвращает привязанных провайдеров и есть ли у аккаунта пароль
+func (h *OAuth2Handler) GetLinkedAccounts(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	accounts, err := h.oauth2Service.GetLinkedAccounts(userID)
+	if err != nil {
+		return oauth2Error(c, err, "Failed to fetch linked accounts")
+	}
+
+	return c.JSON(accounts)
+}
+
+// UnlinkAccount отвязывает провайдера и возвращает оставшиеся способы входа
+func (h *OAuth2Handler) UnlinkAccount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	accounts, err := h.oauth2Service.UnlinkOAuth(userID, c.Params("provider"))
+	if err != nil {
+		return oauth2Error(c, err, "Failed to unlink account")
+	}
+
 	return c.JSON(fiber.Map{
-		"message": "Account unlinked successfully",
+		"message":      "Account unlinked successfully",
+		"has_password": accounts.HasPassword,
+		"identities":   accounts.Identities,
 	})
 }
+
+// consumeFlow проверяет cookie с параметрами входа и удаляет её: каждый state одноразовый
+func (h *OAuth2Handler) consumeFlow(c *fiber.Ctx, provider, state string) (*models.OAuthFlow, error) {
+	value := c.Cookies(oauthStateCookie)
+	h.cookies.Clear(c, oauthStateCookie, oauthStateCookiePath)
+
+	return h.oauth2Service.VerifyFlow(value, provider, state)
+}
+
+func oauth2Error(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidOAuthState), errors.Is(err, services.ErrInvalidLinkToken),
+		errors.Is(err, oauth.ErrUnknownProvider):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrOAuthNotLinked), errors.Is(err, services.ErrUserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrOAuthAccountTaken), errors.Is(err, services.ErrOAuthAlreadyLinked),
+		errors.Is(err, services.ErrLastLoginMethod):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+}
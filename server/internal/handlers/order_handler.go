@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OrderHandler struct {
+	orderService *services.OrderService
+	jwt          *middleware.JWTMiddleware
+}
+
+func NewOrderHandler(orderService *services.OrderService, jwt *middleware.JWTMiddleware) *OrderHandler {
+	return &OrderHandler{
+		orderService: orderService,
+		jwt:          jwt,
+	}
+}
+
+func (h *OrderHandler) PlaceOrder(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.PlaceOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	order, err := h.orderService.PlaceOrder(userID, &req)
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(order)
+}
+
+func (h *OrderHandler) GetOrders(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "12"))
+
+	orders, total, err := h.orderService.GetOrders(userID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch orders",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"orders": orders,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+func (h *OrderHandler) GetOrder(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	order, err := h.orderService.GetOrder(userID, orderID)
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	return c.JSON(order)
+}
+
+func (h *OrderHandler) CancelOrder(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	order, err := h.orderService.CancelOrder(userID, orderID)
+	if err != nil {
+		return orderError(c, err)
+	}
+
+	return c.JSON(order)
+}
+
+func orderError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrProductNotFound),
+		errors.Is(err, services.ErrVariantNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrCartEmpty), errors.Is(err, services.ErrVariantRequired), isValidationError(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrInsufficientStock), errors.Is(err, services.ErrInvalidOrderTransition):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process order",
+		})
+	}
+}
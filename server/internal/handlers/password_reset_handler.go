@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PasswordResetHandler struct {
+	resetService *services.PasswordResetService
+}
+
+func NewPasswordResetHandler(resetService *services.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		resetService: resetService,
+	}
+}
+
+// Forgot всегда отвечает одинаково, даже если аккаунта с таким email нет
+func (h *PasswordResetHandler) Forgot(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.resetService.Forgot(&req); err != nil {
+		if isValidationError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to request password reset",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If an account with this email exists, a password reset link has been sent",
+	})
+}
+
+func (h *PasswordResetHandler) Reset(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.resetService.Reset(&req); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidResetToken), isValidationError(err):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to reset password",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Password has been reset, please log in with the new password",
+	})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/payments"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PaymentHandler struct {
+	paymentService *services.PaymentService
+	jwt            *middleware.JWTMiddleware
+}
+
+func NewPaymentHandler(paymentService *services.PaymentService, jwt *middleware.JWTMiddleware) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+		jwt:            jwt,
+	}
+}
+
+func (h *PaymentHandler) StartPayment(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	response, err := h.paymentService.StartPayment(userID, orderID)
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (h *PaymentHandler) ConfirmPayment(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	var req models.ConfirmPaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	payment, err := h.paymentService.ConfirmPayment(userID, orderID, &req)
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(payment)
+}
+
+func (h *PaymentHandler) Webhook(c *fiber.Ctx) error {
+	duplicate, err := h.paymentService.HandleWebhook(c.Params("provider"), c.Body(), func(key string) string {
+		return c.Get(key)
+	})
+	if err != nil {
+		return paymentError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"received":  true,
+		"duplicate": duplicate,
+	})
+}
+
+func paymentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrPaymentNotFound),
+		errors.Is(err, services.ErrUnknownPaymentProvider):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, payments.ErrInvalidSignature):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, payments.ErrPaymentDeclined):
+		return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrPaymentsNotConfigured):
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrOrderNotPayable), errors.Is(err, services.ErrPaymentNotRefundable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case isValidationError(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process payment",
+		})
+	}
+}
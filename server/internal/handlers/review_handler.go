@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReviewHandler struct {
+	reviewService *services.ReviewService
+	jwt           *middleware.JWTMiddleware
+}
+
+func NewReviewHandler(reviewService *services.ReviewService, jwt *middleware.JWTMiddleware) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		jwt:           jwt,
+	}
+}
+
+func (h *ReviewHandler) GetProductReviews(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	reviews, total, err := h.reviewService.GetProductReviews(productID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reviews",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"reviews": reviews,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func (h *ReviewHandler) CreateReview(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	review, err := h.reviewService.CreateReview(userID, &req)
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(review)
+}
+
+func (h *ReviewHandler) UpdateReview(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	reviewID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review ID",
+		})
+	}
+
+	var req models.ReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	review, err := h.reviewService.UpdateReview(userID, reviewID, &req)
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.JSON(review)
+}
+
+func (h *ReviewHandler) DeleteReview(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	reviewID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review ID",
+		})
+	}
+
+	if err := h.reviewService.DeleteReview(userID, reviewID); err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Review deleted successfully",
+	})
+}
+
+func reviewError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrReviewNotFound), errors.Is(err, services.ErrProductNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrReviewExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, services.ErrReviewNotAllowed):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case isValidationError(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save review",
+		})
+	}
+}
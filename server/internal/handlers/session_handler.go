@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+	jwt            *middleware.JWTMiddleware
+}
+
+func NewSessionHandler(sessionService *services.SessionService, jwt *middleware.JWTMiddleware) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		jwt:            jwt,
+	}
+}
+
+// List возвращает устройства, с которых выполнен вход
+func (h *SessionHandler) List(c *fiber.Ctx) error {
+	claims := h.jwt.GetClaims(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	sessions, err := h.sessionService.List(claims.UserID, claims.SessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": sessions,
+	})
+}
+
+// Revoke завершает одну сессию. Завершение текущей сессии равносильно выходу.
+func (h *SessionHandler) Revoke(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	if err := h.sessionService.Revoke(userID, c.Params("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke session",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Session revoked",
+	})
+}
+
+// RevokeOthers завершает все сессии, кроме текущей
+func (h *SessionHandler) RevokeOthers(c *fiber.Ctx) error {
+	claims := h.jwt.GetClaims(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	revoked, err := h.sessionService.RevokeOthers(claims.UserID, claims.SessionID)
+	if errors.Is(err, services.ErrCurrentSessionUnknown) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Other sessions revoked",
+		"revoked": revoked,
+	})
+}
+
+// clientInfo описывает устройство, с которого пришёл запрос, для списка сессий
+func clientInfo(c *fiber.Ctx) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IP:        c.IP(),
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WishlistHandler struct {
+	wishlistService *services.WishlistService
+	jwt             *middleware.JWTMiddleware
+}
+
+func NewWishlistHandler(wishlistService *services.WishlistService, jwt *middleware.JWTMiddleware) *WishlistHandler {
+	return &WishlistHandler{
+		wishlistService: wishlistService,
+		jwt:             jwt,
+	}
+}
+
+func (h *WishlistHandler) GetWishlist(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	items, err := h.wishlistService.GetWishlist(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch wishlist",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+	})
+}
+
+func (h *WishlistHandler) AddItem(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.AddWishlistItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	items, err := h.wishlistService.AddItem(userID, &req)
+	if err != nil {
+		return wishlistError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"items": items,
+	})
+}
+
+func (h *WishlistHandler) RemoveItem(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	itemID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wishlist item ID",
+		})
+	}
+
+	items, err := h.wishlistService.RemoveItem(userID, itemID)
+	if err != nil {
+		return wishlistError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"items": items,
+	})
+}
+
+func (h *WishlistHandler) MoveToCart(c *fiber.Ctx) error {
+	userID := h.jwt.GetUserID(c)
+	if userID == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Unauthorized",
+		})
+	}
+
+	itemID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wishlist item ID",
+		})
+	}
+
+	var req models.MoveWishlistItemRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request format",
+			})
+		}
+	}
+
+	cart, err := h.wishlistService.MoveToCart(userID, itemID, &req)
+	if err != nil {
+		return wishlistError(c, err)
+	}
+
+	return c.JSON(cart)
+}
+
+func wishlistError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrWishlistItemNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return cartError(c, err)
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer отправляет письма через SMTP сервер. Если сервер поддерживает
+// STARTTLS, соединение шифруется автоматически.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+	// sender - адрес из from без имени, нужен для команды MAIL FROM
+	sender string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	sender := from
+	if addr, err := mail.ParseAddress(from); err == nil {
+		sender = addr.Address
+	}
+
+	return &SMTPMailer{
+		addr:   net.JoinHostPort(host, port),
+		auth:   auth,
+		from:   from,
+		sender: sender,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	return smtp.SendMail(m.addr, m.auth, m.sender, []string{msg.To}, render(m.from, msg))
+}
@@ -1,6 +1,10 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
 	"strings"
 	"time"
 
@@ -8,55 +12,174 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var (
+	ErrTokenRevoked     = errors.New("token has been revoked")
+	ErrInvalidTokenType = errors.New("invalid token type")
+	ErrUnknownKeyID     = errors.New("unknown token key id")
+)
+
+// TokenTypeMFA - промежуточный токен входа, который можно обменять на пару
+// access + refresh только вместе с кодом второго фактора
+const TokenTypeMFA = "mfa"
+
+// RevocationChecker проверяет, не отозван ли выданный токен
+type RevocationChecker interface {
+	IsRevoked(tokenID, sessionID string, userID int, issuedAt time.Time) (bool, error)
+}
+
 type JWTClaims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
+	UserID int      `json:"user_id"`
+	Email  string   `json:"email"`
+	Roles  []string `json:"roles"`
+	// Type пуст у access токенов
+	Type string `json:"typ,omitempty"`
+	// SessionID - сессия (вход с устройства), которой выдан access токен
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type JWTMiddleware struct {
-	secretKey string
+	signingKey *JWTKey
+	// keys - ключи проверки по kid: ключ подписи и прежние ключи, токены которых ещё действуют
+	keys        map[string]*JWTKey
+	accessTTL   time.Duration
+	revocations RevocationChecker
 }
 
-func NewJWTMiddleware(secretKey string) *JWTMiddleware {
+func NewJWTMiddleware(signingKey *JWTKey, verificationKeys []*JWTKey, accessTTL time.Duration, revocations RevocationChecker) *JWTMiddleware {
+	keys := map[string]*JWTKey{signingKey.ID: signingKey}
+	for _, key := range verificationKeys {
+		if _, ok := keys[key.ID]; !ok {
+			keys[key.ID] = key
+		}
+	}
+
 	return &JWTMiddleware{
-		secretKey: secretKey,
+		signingKey:  signingKey,
+		keys:        keys,
+		accessTTL:   accessTTL,
+		revocations: revocations,
 	}
 }
 
-func (j *JWTMiddleware) GenerateToken(userID int, email string) (string, error) {
-	claims := JWTClaims{
+// AccessTTL - время жизни access токена
+func (j *JWTMiddleware) AccessTTL() time.Duration {
+	return j.accessTTL
+}
+
+func (j *JWTMiddleware) GenerateToken(userID int, email string, roles []string, sessionID string) (string, error) {
+	return j.sign(JWTClaims{
+		UserID:    userID,
+		Email:     email,
+		Roles:     roles,
+		SessionID: sessionID,
+	}, j.accessTTL)
+}
+
+// GenerateMFAToken выдаёт короткоживущий токен, подтверждающий, что пароль уже проверен
+func (j *JWTMiddleware) GenerateMFAToken(userID int, ttl time.Duration) (string, error) {
+	return j.sign(JWTClaims{
 		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+		Type:   TokenTypeMFA,
+	}, ttl)
+}
+
+func (j *JWTMiddleware) sign(claims JWTClaims, ttl time.Duration) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        tokenID,
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+
+	token := jwt.NewWithClaims(j.signingKey.method, claims)
+	token.Header["kid"] = j.signingKey.ID
+	return token.SignedString(j.signingKey.private)
+}
+
+// JWKS возвращает открытые ключи, которыми можно проверить выданные токены
+func (j *JWTMiddleware) JWKS() []JWK {
+	keys := []JWK{j.signingKey.JWK()}
+	for id, key := range j.keys {
+		if id != j.signingKey.ID {
+			keys = append(keys, key.JWK())
+		}
+	}
+	// Порядок стабилен, чтобы ответ не менялся между запросами
+	sort.Slice(keys[1:], func(a, b int) bool { return keys[a+1].Kid < keys[b+1].Kid })
+	return keys
 }
 
+// ValidateToken проверяет access токен
 func (j *JWTMiddleware) ValidateToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.secretKey), nil
-	})
+	return j.validate(tokenString, "")
+}
+
+// ValidateMFAToken проверяет промежуточный токен входа с двумя факторами
+func (j *JWTMiddleware) ValidateMFAToken(tokenString string) (*JWTClaims, error) {
+	return j.validate(tokenString, TokenTypeMFA)
+}
+
+func (j *JWTMiddleware) validate(tokenString, tokenType string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, j.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrInvalidKey
+	}
+	if claims.Type != tokenType {
+		return nil, ErrInvalidTokenType
+	}
+
+	if j.revocations != nil {
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+
+		revoked, err := j.revocations.IsRevoked(claims.ID, claims.SessionID, claims.UserID, issuedAt)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
 	}
 
-	return nil, jwt.ErrInvalidKey
+	return claims, nil
+}
+
+// verificationKey выбирает ключ по kid; алгоритм токена должен совпадать с типом ключа
+func (j *JWTMiddleware) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.public, nil
 }
 
 func (j *JWTMiddleware) JWTAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// Токен уже проверен OptionalJWTAuth, подключённым для всего приложения
+		if j.GetClaims(c) != nil {
+			return c.Next()
+		}
+
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -79,13 +202,48 @@ func (j *JWTMiddleware) JWTAuth() fiber.Handler {
 			})
 		}
 
-		c.Locals("user_id", claims.UserID)
-		c.Locals("user_email", claims.Email)
+		setClaims(c, claims)
 
 		return c.Next()
 	}
 }
 
+// OptionalJWTAuth заполняет данные пользователя, если передан валидный токен,
+// но не отклоняет анонимные запросы
+func (j *JWTMiddleware) OptionalJWTAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if j.GetClaims(c) != nil {
+			return c.Next()
+		}
+
+		tokenParts := strings.Split(c.Get("Authorization"), " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			return c.Next()
+		}
+
+		if claims, err := j.ValidateToken(tokenParts[1]); err == nil {
+			setClaims(c, claims)
+		}
+
+		return c.Next()
+	}
+}
+
+func setClaims(c *fiber.Ctx, claims *JWTClaims) {
+	c.Locals("user_id", claims.UserID)
+	c.Locals("user_email", claims.Email)
+	c.Locals("user_roles", claims.Roles)
+	c.Locals("token_claims", claims)
+}
+
+// GetClaims возвращает claims проверенного токена текущего запроса
+func (j *JWTMiddleware) GetClaims(c *fiber.Ctx) *JWTClaims {
+	if claims, ok := c.Locals("token_claims").(*JWTClaims); ok {
+		return claims
+	}
+	return nil
+}
+
 func (j *JWTMiddleware) GetUserID(c *fiber.Ctx) int {
 	if userID, ok := c.Locals("user_id").(int); ok {
 		return userID
@@ -99,3 +257,18 @@ func (j *JWTMiddleware) GetUserEmail(c *fiber.Ctx) string {
 	}
 	return ""
 }
+
+func (j *JWTMiddleware) GetUserRoles(c *fiber.Ctx) []string {
+	if roles, ok := c.Locals("user_roles").([]string); ok {
+		return roles
+	}
+	return nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported jwt key: expected RSA (2048 bits or more) or Ed25519")
+
+// JWTKey - ключ подписи access токенов. ID - отпечаток открытого ключа (RFC 7638),
+// поэтому kid не меняется, когда ключ из ключа подписи становится ключом проверки.
+type JWTKey struct {
+	ID     string
+	method jwt.SigningMethod
+	// private задан только у ключа подписи
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// JWK - открытый ключ в формате JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// ParseSigningKey разбирает закрытый ключ в PEM: RSA (RS256) или Ed25519 (EdDSA)
+func ParseSigningKey(data string) (*JWTKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("jwt signing key is not a PEM block")
+	}
+
+	key, err := parseKey(block)
+	if err != nil {
+		return nil, err
+	}
+	if key.private == nil {
+		return nil, errors.New("jwt signing key must be a private key")
+	}
+	return key, nil
+}
+
+// ParseVerificationKeys разбирает открытые (или закрытые) ключи в PEM. В одной строке
+// может быть несколько PEM блоков.
+func ParseVerificationKeys(data ...string) ([]*JWTKey, error) {
+	var keys []*JWTKey
+	for _, item := range data {
+		rest := []byte(item)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+
+			key, err := parseKey(block)
+			if err != nil {
+				return nil, err
+			}
+			// Для проверки подписи достаточно открытой части
+			key.private = nil
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// GenerateSigningKey создаёт временный ключ Ed25519. Токены, подписанные им,
+// перестают приниматься после перезапуска.
+func GenerateSigningKey() (*JWTKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newJWTKey(private, private.Public())
+}
+
+// JWK возвращает открытую часть ключа для публикации в JWKS
+func (k *JWTKey) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Alg: k.method.Alg(), Use: "sig"}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+func parseKey(block *pem.Block) (*JWTKey, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwt private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, ErrUnsupportedKey
+		}
+		return newJWTKey(signer, signer.Public())
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwt private key: %w", err)
+		}
+		return newJWTKey(key, key.Public())
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwt public key: %w", err)
+		}
+		return newJWTKey(nil, key)
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwt public key: %w", err)
+		}
+		return newJWTKey(nil, key)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q in jwt key", block.Type)
+	}
+}
+
+func newJWTKey(private crypto.Signer, public crypto.PublicKey) (*JWTKey, error) {
+	key := &JWTKey{private: private, public: public}
+
+	// Члены JWK для отпечатка - в лексикографическом порядке, как требует RFC 7638
+	var thumbprintInput any
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, ErrUnsupportedKey
+		}
+		key.method = jwt.SigningMethodRS256
+		jwk := key.JWK()
+		thumbprintInput = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+		jwk := key.JWK()
+		thumbprintInput = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	encoded, err := json.Marshal(thumbprintInput)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(encoded)
+	key.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return key, nil
+}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func encodePEM(t *testing.T, blockType string, der []byte, err error) string {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+func publicPEM(t *testing.T, public any) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	return encodePEM(t, "PUBLIC KEY", der, err)
+}
+
+func decodeBase64(t *testing.T, value string) []byte {
+	t.Helper()
+
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// Отпечатки ключей из примеров RFC 7638 (раздел 3.1) и RFC 8037 (приложение A.3)
+func TestKeyIDIsRFC7638Thumbprint(t *testing.T) {
+	rsaKey := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(decodeBase64(t, "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")),
+		E: 65537,
+	}
+	edKey := ed25519.PublicKey(decodeBase64(t, "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"))
+
+	tests := []struct {
+		name string
+		pem  string
+		kid  string
+	}{
+		{"RSA", encodePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(rsaKey), nil), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"},
+		{"Ed25519", publicPEM(t, edKey), "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseVerificationKeys(tt.pem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 1 || keys[0].ID != tt.kid {
+				t.Fatalf("kid = %v, want %s", keys, tt.kid)
+			}
+		})
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := func(key any) string {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		return encodePEM(t, "PRIVATE KEY", der, err)
+	}
+
+	tests := []struct {
+		name    string
+		private string
+		public  string
+		alg     string
+	}{
+		{"RSA PKCS#8", pkcs8(rsaKey), publicPEM(t, &rsaKey.PublicKey), "RS256"},
+		{"RSA PKCS#1", encodePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil), encodePEM(t, "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), nil), "RS256"},
+		{"Ed25519", pkcs8(edPrivate), publicPEM(t, edPublic), "EdDSA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseSigningKey(tt.private)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.private == nil || key.method.Alg() != tt.alg {
+				t.Fatalf("signing key alg = %s, want %s with a private key", key.method.Alg(), tt.alg)
+			}
+
+			// kid ключа подписи совпадает с kid его открытой части, поэтому ротация не меняет kid
+			verification, err := ParseVerificationKeys(tt.public)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(verification) != 1 || verification[0].ID != key.ID {
+				t.Fatalf("verification key id does not match signing key id %s", key.ID)
+			}
+
+			jwk := key.JWK()
+			if jwk.Kid != key.ID || jwk.Alg != tt.alg || jwk.Use != "sig" {
+				t.Fatalf("unexpected JWK %+v", jwk)
+			}
+		})
+	}
+}
+
+func TestParseSigningKeyRejectsUnsupportedKeys(t *testing.T) {
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+
+	tests := []struct {
+		name string
+		pem  string
+		want error
+	}{
+		{"RSA shorter than 2048 bits", encodePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(smallRSA), nil), ErrUnsupportedKey},
+		{"ECDSA", encodePEM(t, "PRIVATE KEY", ecDER, err), ErrUnsupportedKey},
+		{"public key", publicPEM(t, edPublic), nil},
+		{"not PEM", "not a key", nil},
+		{"unknown PEM block", encodePEM(t, "CERTIFICATE REQUEST", []byte{1}, nil), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSigningKey(tt.pem)
+			if err == nil {
+				t.Fatal("unsupported key was accepted")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVerificationKeysKeepsOnlyPublicPart(t *testing.T) {
+	first, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, secondPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondDER, err := x509.MarshalPKCS8PrivateKey(secondPrivate)
+
+	// Несколько PEM блоков в одной строке, в том числе закрытый ключ
+	bundle := publicPEM(t, first.public) + encodePEM(t, "PRIVATE KEY", secondDER, err)
+	keys, err := ParseVerificationKeys(bundle, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("parsed %d keys, want 2", len(keys))
+	}
+	if keys[0].ID != first.ID {
+		t.Fatalf("kid = %s, want %s", keys[0].ID, first.ID)
+	}
+	for _, key := range keys {
+		if key.private != nil {
+			t.Fatal("verification key kept its private part")
+		}
+	}
+}
+
+func TestJWTMiddlewareAcceptsPreviousKey(t *testing.T) {
+	previous, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := NewJWTMiddleware(previous, nil, time.Minute, nil).GenerateToken(1, "user@example.com", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Прежний ключ - в ключах проверки: выданный им токен ещё принимается
+	previousPublic, err := ParseVerificationKeys(publicPEM(t, previous.public))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := NewJWTMiddleware(current, previousPublic, time.Minute, nil)
+	if _, err := rotated.ValidateToken(token); err != nil {
+		t.Fatalf("token signed with the previous key: %v", err)
+	}
+	if jwks := rotated.JWKS(); len(jwks) != 2 || jwks[0].Kid != current.ID {
+		t.Fatalf("JWKS = %+v, want current key first and the previous key", jwks)
+	}
+
+	// Без прежнего ключа токен отклоняется
+	if _, err := NewJWTMiddleware(current, nil, time.Minute, nil).ValidateToken(token); !errors.Is(err, ErrUnknownKeyID) {
+		t.Fatalf("err = %v, want ErrUnknownKeyID", err)
+	}
+}
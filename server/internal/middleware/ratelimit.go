@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"tenderness/internal/domain/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Названия политик ограничения частоты запросов
+const (
+	RateLimitGlobal  = "global"
+	RateLimitAuth    = "auth"
+	RateLimitCatalog = "catalog"
+	RateLimitUser    = "user"
+)
+
+// RateLimit - политика token bucket: Requests запросов за Per, корзина на Burst
+// запросов (по умолчанию Requests). Нулевой Requests отключает политику.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+func (l RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// RateLimitStore хранит корзины токенов. Реализации: MemoryRateLimitStore
+// для одного экземпляра и repository.RateLimitRepository (Postgres) для нескольких.
+type RateLimitStore interface {
+	Take(key string, rate, burst float64, now time.Time) (*models.RateLimitBucket, bool, error)
+	DeleteStale(before time.Time) (int64, error)
+}
+
+// RateLimiter ограничивает частоту запросов по IP или, если пользователь
+// уже определён JWTAuth/OptionalJWTAuth, по его ID
+type RateLimiter struct {
+	store    RateLimitStore
+	jwt      *JWTMiddleware
+	policies map[string]RateLimit
+	now      func() time.Time
+}
+
+func NewRateLimiter(store RateLimitStore, jwt *JWTMiddleware, policies map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		store:    store,
+		jwt:      jwt,
+		policies: policies,
+		now:      time.Now,
+	}
+}
+
+// Limit возвращает middleware для политики name: вошедшие пользователи считаются по ID,
+// гости по IP. Ответы содержат заголовки RateLimit-Limit, RateLimit-Remaining
+// и RateLimit-Reset, отказ - 429 с Retry-After.
+// Если хранилище недоступно, запрос пропускается: лимит не должен ронять API.
+func (l *RateLimiter) Limit(name string) fiber.Handler {
+	return l.limit(name, true)
+}
+
+// LimitByIP как Limit, но всегда считает запросы по IP. Нужен для входа и регистрации,
+// где токен одного из многих аккаунтов не должен давать отдельный лимит.
+func (l *RateLimiter) LimitByIP(name string) fiber.Handler {
+	return l.limit(name, false)
+}
+
+func (l *RateLimiter) limit(name string, byUser bool) fiber.Handler {
+	policy, ok := l.policies[name]
+	if !ok || !policy.enabled() {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	rate, burst := policy.rate(), policy.burst()
+
+	return func(c *fiber.Ctx) error {
+		key := name + ":ip:" + c.IP()
+		if userID := l.jwt.GetUserID(c); byUser && userID != 0 {
+			key = fmt.Sprintf("%s:user:%d", name, userID)
+		}
+
+		bucket, allowed, err := l.store.Take(key, rate, burst, l.now())
+		if err != nil {
+			log.Printf("Rate limit store error for %s: %v", key, err)
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(int(burst)))
+		c.Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(bucket.Tokens))))
+		c.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil((burst-bucket.Tokens)/rate))))
+		c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Requests, int(policy.Per.Seconds())))
+
+		if !allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil((1-bucket.Tokens)/rate))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many requests",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// MemoryRateLimitStore хранит корзины в памяти процесса
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*models.RateLimitBucket
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*models.RateLimitBucket),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, rate, burst float64, now time.Time) (*models.RateLimitBucket, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = models.NewRateLimitBucket(key, burst, now)
+		s.buckets[key] = bucket
+	}
+
+	allowed := bucket.Take(now, rate, burst)
+	snapshot := *bucket
+	return &snapshot, allowed, nil
+}
+
+func (s *MemoryRateLimitStore) DeleteStale(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, bucket := range s.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(s.buckets, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var rateLimitStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	const rate, burst = 2.0, 3.0
+
+	tests := []struct {
+		name    string
+		at      time.Duration
+		allowed bool
+		tokens  float64
+	}{
+		{"new bucket is full", 0, true, 2},
+		{"burst", 0, true, 1},
+		{"burst", 0, true, 0},
+		{"empty", 0, false, 0},
+		{"partial refill is not enough", 250 * time.Millisecond, false, 0.5},
+		{"refill", 500 * time.Millisecond, true, 0},
+		{"clock going back does not refill", 0, false, 0},
+		{"refill stops at burst", time.Hour, true, 2},
+	}
+	for _, tt := range tests {
+		bucket, allowed, err := store.Take("key", rate, burst, rateLimitStart.Add(tt.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != tt.allowed || bucket.Tokens != tt.tokens {
+			t.Fatalf("%s at +%s: allowed = %v, tokens = %v, want %v, %v", tt.name, tt.at, allowed, bucket.Tokens, tt.allowed, tt.tokens)
+		}
+	}
+
+	// Корзины разных ключей независимы
+	if bucket, _, _ := store.Take("other", rate, burst, rateLimitStart); bucket.Tokens != 2 {
+		t.Fatalf("tokens of another key = %v, want 2", bucket.Tokens)
+	}
+
+	deleted, err := store.DeleteStale(rateLimitStart.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("DeleteStale deleted %d buckets, want 1", deleted)
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	now := rateLimitStart
+	// 0,5 запроса в секунду, корзина на 2 запроса
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), nil, map[string]RateLimit{
+		RateLimitCatalog: {Requests: 30, Per: time.Minute, Burst: 2},
+	})
+	limiter.now = func() time.Time { return now }
+
+	app := fiber.New()
+	app.Get("/", limiter.LimitByIP(RateLimitCatalog), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		at         time.Duration
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{0, fiber.StatusOK, "1", "2", ""},
+		{0, fiber.StatusOK, "0", "4", ""},
+		{0, fiber.StatusTooManyRequests, "0", "4", "2"},
+		{time.Second, fiber.StatusTooManyRequests, "0", "3", "1"},
+		{2 * time.Second, fiber.StatusOK, "0", "4", ""},
+		{time.Hour, fiber.StatusOK, "1", "2", ""},
+	}
+	for _, tt := range tests {
+		now = rateLimitStart.Add(tt.at)
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{resp.Header.Get("RateLimit-Remaining"), resp.Header.Get("RateLimit-Reset"), resp.Header.Get(fiber.HeaderRetryAfter)}
+		want := []string{tt.remaining, tt.reset, tt.retryAfter}
+		if resp.StatusCode != tt.status || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Fatalf("at +%s: status %d, remaining/reset/retry-after %q, want %d, %q", tt.at, resp.StatusCode, got, tt.status, want)
+		}
+		if limit, policy := resp.Header.Get("RateLimit-Limit"), resp.Header.Get("RateLimit-Policy"); limit != "2" || policy != "30;w=60" {
+			t.Fatalf("RateLimit-Limit = %q, RateLimit-Policy = %q", limit, policy)
+		}
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwt := NewJWTMiddleware(key, nil, time.Minute, nil)
+	store := NewMemoryRateLimitStore()
+	limiter := NewRateLimiter(store, jwt, map[string]RateLimit{
+		RateLimitUser: {Requests: 1, Per: time.Minute},
+		RateLimitAuth: {Requests: 1, Per: time.Minute},
+	})
+
+	ok := func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	}
+	app := fiber.New()
+	app.Use(jwt.OptionalJWTAuth())
+	app.Get("/user", limiter.Limit(RateLimitUser), ok)
+	app.Get("/login", limiter.LimitByIP(RateLimitAuth), ok)
+
+	request := func(path string, userID int) int {
+		t.Helper()
+
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		if userID != 0 {
+			token, err := jwt.GenerateToken(userID, "user@example.com", nil, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// Вошедшие пользователи и гость за одним IP не делят лимит
+	for _, userID := range []int{1, 2, 0} {
+		if status := request("/user", userID); status != fiber.StatusOK {
+			t.Fatalf("first request of user %d: status %d", userID, status)
+		}
+	}
+	if status := request("/user", 1); status != fiber.StatusTooManyRequests {
+		t.Fatalf("second request of user 1: status %d, want 429", status)
+	}
+
+	// LimitByIP считает всех за одним IP вместе, даже с разными токенами
+	if status := request("/login", 1); status != fiber.StatusOK {
+		t.Fatalf("first login: status %d", status)
+	}
+	if status := request("/login", 2); status != fiber.StatusTooManyRequests {
+		t.Fatalf("login of another user from the same IP: status %d, want 429", status)
+	}
+
+	for _, key := range []string{"user:user:1", "user:user:2", "user:ip:0.0.0.0", "auth:ip:0.0.0.0"} {
+		if _, ok := store.buckets[key]; !ok {
+			t.Errorf("no bucket %s", key)
+		}
+	}
+	if len(store.buckets) != 4 {
+		t.Errorf("buckets = %d, want 4", len(store.buckets))
+	}
+}
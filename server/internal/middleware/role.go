@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// RoleMiddleware проверяет роли и права пользователя по ролям из токена.
+// Ставится после JWTAuth; права ролей загружаются из БД при старте приложения.
+type RoleMiddleware struct {
+	jwt         *JWTMiddleware
+	permissions map[string]map[string]bool
+}
+
+func NewRoleMiddleware(jwt *JWTMiddleware, permissionsByRole map[string][]string) *RoleMiddleware {
+	permissions := make(map[string]map[string]bool, len(permissionsByRole))
+	for role, granted := range permissionsByRole {
+		permissions[role] = make(map[string]bool, len(granted))
+		for _, permission := range granted {
+			permissions[role][permission] = true
+		}
+	}
+
+	return &RoleMiddleware{
+		jwt:         jwt,
+		permissions: permissions,
+	}
+}
+
+// RequireRole пропускает пользователя хотя бы с одной из ролей
+func (m *RoleMiddleware) RequireRole(allowed ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, role := range m.jwt.GetUserRoles(c) {
+			for _, r := range allowed {
+				if r == role {
+					return c.Next()
+				}
+			}
+		}
+		return forbidden(c)
+	}
+}
+
+// RequirePermission пропускает пользователя, у ролей которого есть все перечисленные права
+func (m *RoleMiddleware) RequirePermission(required ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, permission := range required {
+			if !m.HasPermission(m.jwt.GetUserRoles(c), permission) {
+				return forbidden(c)
+			}
+		}
+		return c.Next()
+	}
+}
+
+func (m *RoleMiddleware) HasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		if m.permissions[role][permission] {
+			return true
+		}
+	}
+	return false
+}
+
+func forbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": "Forbidden",
+	})
+}
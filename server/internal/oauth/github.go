@@ -0,0 +1,113 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/oauth2"
+)
+
+// GitHubProvider - вход через GitHub. GitHub не поддерживает OpenID Connect,
+// поэтому профиль и email берутся из REST API
+type GitHubProvider struct {
+	name   string
+	config *oauth2.Config
+	apiURL string
+}
+
+func NewGitHubProvider(config Config) *GitHubProvider {
+	return &GitHubProvider{
+		name: config.Name,
+		config: &oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Scopes:       config.Scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  "https://github.com/login/oauth/authorize",
+				TokenURL: "https://github.com/login/oauth/access_token",
+			},
+		},
+		apiURL: "https://api.github.com",
+	}
+}
+
+func (p *GitHubProvider) Name() string {
+	return p.name
+}
+
+func (p *GitHubProvider) UsesNonce() bool {
+	return false
+}
+
+func (p *GitHubProvider) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	return p.config.AuthCodeURL(req.State, authCodeOptions(req)...), nil
+}
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code string, req AuthRequest) (*Identity, error) {
+	ctx = withHTTPClient(ctx)
+
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+	client := p.config.Client(ctx, token)
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(client, p.apiURL+"/user", &user); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	// Email нужно запрашивать отдельно: в профиле он есть, только если пользователь сделал его публичным
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(client, p.apiURL+"/user/emails", &emails); err != nil {
+		return nil, fmt.Errorf("failed to get user emails: %w", err)
+	}
+
+	identity := &Identity{
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+	if identity.Email == "" {
+		return nil, errors.New("no primary email found")
+	}
+
+	return identity, nil
+}
+
+func getJSON(client *http.Client, url string, dst any) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
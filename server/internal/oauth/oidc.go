@@ -0,0 +1,393 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+// jwksRefreshInterval - не чаще этого ключи перечитываются из-за незнакомого kid
+const jwksRefreshInterval = time.Minute
+
+// OIDCProvider - вход через любого провайдера OpenID Connect. Адреса авторизации,
+// токенов, userinfo и ключей подписи берутся из {issuer}/.well-known/openid-configuration,
+// поэтому для нового провайдера достаточно указать issuer и данные клиента.
+type OIDCProvider struct {
+	config Config
+	issuer string
+
+	// fetches объединяет одновременные запросы к провайдеру; сами запросы идут без mu,
+	// чтобы медленный провайдер не блокировал проверку токенов уже известными ключами
+	fetches singleflight.Group
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]any
+	keysAt    time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims - claims из id_token и ответа userinfo
+type oidcClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string    `json:"nonce"`
+	Email         string    `json:"email"`
+	EmailVerified claimBool `json:"email_verified"`
+	Name          string    `json:"name"`
+	GivenName     string    `json:"given_name"`
+	FamilyName    string    `json:"family_name"`
+	Picture       string    `json:"picture"`
+}
+
+// claimBool принимает и true, и "true": часть провайдеров отдаёт email_verified строкой
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	*b = claimBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// NewOIDCProvider не обращается к провайдеру: discovery выполняется при первом
+// входе, чтобы недоступный провайдер не мешал запуску приложения
+func NewOIDCProvider(config Config, issuer string) *OIDCProvider {
+	return &OIDCProvider{
+		config: config,
+		issuer: strings.TrimRight(issuer, "/"),
+	}
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+func (p *OIDCProvider) UsesNonce() bool {
+	return true
+}
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	config, _, err := p.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(req.State, authCodeOptions(req)...), nil
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, req AuthRequest) (*Identity, error) {
+	ctx = withHTTPClient(ctx)
+
+	config, discovery, err := p.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: missing in %s response", ErrInvalidIDToken, p.config.Name)
+	}
+
+	claims, err := p.verifyIDToken(ctx, discovery, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if req.Nonce != "" && subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(req.Nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	// Часть провайдеров кладёт в id_token только sub, остальное отдаёт userinfo
+	if discovery.UserinfoEndpoint != "" && (claims.Email == "" || claims.Name == "") {
+		var userinfo oidcClaims
+		if err := getJSON(config.Client(ctx, token), discovery.UserinfoEndpoint, &userinfo); err != nil {
+			return nil, fmt.Errorf("failed to get user info: %w", err)
+		}
+		if userinfo.Subject != claims.Subject {
+			return nil, errors.New("userinfo subject does not match id_token")
+		}
+		mergeClaims(claims, &userinfo)
+	}
+
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		AvatarURL:     claims.Picture,
+	}, nil
+}
+
+func mergeClaims(claims, userinfo *oidcClaims) {
+	if claims.Email == "" {
+		claims.Email = userinfo.Email
+		claims.EmailVerified = userinfo.EmailVerified
+	}
+	if claims.Name == "" {
+		claims.Name = userinfo.Name
+	}
+	if claims.GivenName == "" {
+		claims.GivenName = userinfo.GivenName
+	}
+	if claims.FamilyName == "" {
+		claims.FamilyName = userinfo.FamilyName
+	}
+	if claims.Picture == "" {
+		claims.Picture = userinfo.Picture
+	}
+}
+
+// verifyIDToken проверяет подпись id_token ключами провайдера, издателя,
+// получателя и срок действия
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, discovery *oidcDiscovery, rawIDToken string) (*oidcClaims, error) {
+	claims := &oidcClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.key(ctx, discovery, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: sub is missing", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+func (p *OIDCProvider) oauth2Config(ctx context.Context) (*oauth2.Config, *oidcDiscovery, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       p.config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}, discovery, nil
+}
+
+// discover загружает и кэширует документ discovery. Неудачная попытка не
+// кэшируется: следующий вход попробует снова.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	discovery := p.discovery
+	p.mu.Unlock()
+	if discovery != nil {
+		return discovery, nil
+	}
+
+	result, err, _ := p.fetches.Do("discovery", func() (any, error) {
+		var discovery oidcDiscovery
+		if err := fetchJSON(ctx, p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, fmt.Errorf("failed to discover %s: %w", p.config.Name, err)
+		}
+		// OpenID Connect Discovery 4.3: issuer в документе должен совпадать с настроенным
+		if strings.TrimRight(discovery.Issuer, "/") != p.issuer {
+			return nil, fmt.Errorf("failed to discover %s: issuer %q does not match %q", p.config.Name, discovery.Issuer, p.issuer)
+		}
+		if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("failed to discover %s: endpoints are missing", p.config.Name)
+		}
+
+		p.mu.Lock()
+		p.discovery = &discovery
+		p.mu.Unlock()
+		return &discovery, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*oidcDiscovery), nil
+}
+
+// key возвращает ключ подписи по kid. Незнакомый kid означает, что провайдер
+// сменил ключи, и набор ключей перечитывается.
+func (p *OIDCProvider) key(ctx context.Context, discovery *oidcDiscovery, kid string) (any, error) {
+	p.mu.Lock()
+	key, ok := p.lookupKey(kid)
+	recent := time.Since(p.keysAt) < jwksRefreshInterval
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if recent {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	_, err, _ := p.fetches.Do("jwks", func() (any, error) {
+		keys, err := fetchKeys(ctx, discovery.JWKSURI)
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		p.keys = keys
+		p.keysAt = time.Now()
+		p.mu.Unlock()
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	key, ok = p.lookupKey(kid)
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetchKeys загружает набор ключей подписи. Ключи неподдерживаемых типов
+// пропускаются, остальные остаются в работе.
+func fetchKeys(ctx context.Context, url string) (map[string]any, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := fetchJSON(ctx, url, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// lookupKey без kid допускает только единственный ключ в наборе. Вызывается под mu.
+func (p *OIDCProvider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey - открытый ключ в формате JWK (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC key coordinates")
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func fetchJSON(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
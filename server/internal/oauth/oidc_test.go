@@ -0,0 +1,362 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIssuer - провайдер OpenID Connect на httptest: discovery, JWKS, токены и userinfo
+type testIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	signer crypto.Signer
+	method jwt.SigningMethod
+	kid    string
+	// claims id_token, который вернёт следующий обмен кода
+	claims jwt.MapClaims
+	// userinfo - ответ userinfo; nil, если провайдер его не отдаёт
+	userinfo map[string]any
+
+	discoveryRequests atomic.Int32
+	jwksRequests      atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{t: t, signer: key, method: jwt.SigningMethodRS256, kid: "rsa-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer.discoveryRequests.Add(1)
+		writeJSON(w, map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/authorize",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"userinfo_endpoint":      issuer.server.URL + "/userinfo",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.jwksRequests.Add(1)
+		writeJSON(w, map[string]any{"keys": []any{
+			publicJWK(t, issuer.kid, issuer.signer.Public()),
+			// Ключ шифрования и ключ неизвестного типа пропускаются
+			map[string]string{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+			map[string]string{"kty": "oct", "kid": "hmac-1", "k": "c2VjcmV0"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "test-code" || r.PostForm.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(issuer.method, issuer.claims)
+		token.Header["kid"] = issuer.kid
+		idToken, err := token.SignedString(issuer.signer)
+		if err != nil {
+			t.Error(err)
+		}
+		writeJSON(w, map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if issuer.userinfo == nil || r.Header.Get("Authorization") != "Bearer access" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, issuer.userinfo)
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	issuer.claims = issuer.validClaims()
+	return issuer
+}
+
+func (i *testIssuer) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            i.server.URL,
+		"aud":            "client-id",
+		"sub":            "user-1",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          "nonce-1",
+		"email":          "user@example.com",
+		"email_verified": "true",
+		"name":           "Test User",
+	}
+}
+
+func (i *testIssuer) provider() *OIDCProvider {
+	return NewOIDCProvider(Config{
+		Name:        "test",
+		ClientID:    "client-id",
+		RedirectURL: "http://localhost/api/oauth2/test/callback",
+		Scopes:      []string{"openid", "email", "profile"},
+	}, i.server.URL+"/")
+}
+
+func (i *testIssuer) exchange(p *OIDCProvider) (*Identity, error) {
+	return p.Exchange(context.Background(), "test-code", AuthRequest{CodeVerifier: "verifier", Nonce: "nonce-1"})
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// publicJWK кодирует открытый ключ в JWK
+func publicJWK(t *testing.T, kid string, public crypto.PublicKey) map[string]string {
+	t.Helper()
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		raw, err := key.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return map[string]string{"kty": "EC", "kid": kid, "crv": key.Curve.Params().Name, "x": b64(raw[1 : 1+size]), "y": b64(raw[1+size:])}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64(key)}
+	default:
+		t.Fatalf("unsupported key %T", public)
+		return nil
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := issuer.provider()
+
+	authURL, err := p.AuthCodeURL(context.Background(), AuthRequest{State: "state-1", CodeVerifier: "verifier", Nonce: "nonce-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{issuer.server.URL + "/authorize?", "state=state-1", "nonce=nonce-1", "code_challenge_method=S256", "client_id=client-id"} {
+		if !strings.Contains(authURL, part) {
+			t.Errorf("auth URL %s does not contain %s", authURL, part)
+		}
+	}
+
+	identity, err := issuer.exchange(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Subject: "user-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+
+	// Discovery и ключи кэшируются
+	if _, err := issuer.exchange(p); err != nil {
+		t.Fatal(err)
+	}
+	if got := issuer.discoveryRequests.Load(); got != 1 {
+		t.Errorf("discovery fetched %d times, want 1", got)
+	}
+	if got := issuer.jwksRequests.Load(); got != 1 {
+		t.Errorf("jwks fetched %d times, want 1", got)
+	}
+}
+
+func TestOIDCExchangeMergesUserinfo(t *testing.T) {
+	issuer := newTestIssuer(t)
+	delete(issuer.claims, "email")
+	delete(issuer.claims, "email_verified")
+	delete(issuer.claims, "name")
+	issuer.userinfo = map[string]any{"sub": "user-1", "email": "info@example.com", "email_verified": true, "name": "From Userinfo", "picture": "https://example.com/a.png"}
+
+	identity, err := issuer.exchange(issuer.provider())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "info@example.com" || !identity.EmailVerified || identity.Name != "From Userinfo" || identity.AvatarURL != "https://example.com/a.png" {
+		t.Fatalf("userinfo was not merged: %+v", *identity)
+	}
+
+	issuer.userinfo["sub"] = "someone-else"
+	if _, err := issuer.exchange(issuer.provider()); err == nil {
+		t.Fatal("userinfo for another subject was accepted")
+	}
+}
+
+func TestOIDCExchangeRejectsInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+		want   error
+	}{
+		{"wrong nonce", func(c jwt.MapClaims) { c["nonce"] = "other" }, ErrNonceMismatch},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }, ErrInvalidIDToken},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, ErrInvalidIDToken},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, ErrInvalidIDToken},
+		{"missing subject", func(c jwt.MapClaims) { delete(c, "sub") }, ErrInvalidIDToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			tt.modify(issuer.claims)
+
+			if _, err := issuer.exchange(issuer.provider()); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeRejectsUnknownSigningKey(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := issuer.provider()
+	if _, err := issuer.exchange(p); err != nil {
+		t.Fatal(err)
+	}
+
+	// Токен подписан ключом, которого нет в JWKS
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.validClaims())
+	token.Header["kid"] = issuer.kid
+	forged, err := token.SignedString(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discovery, err := p.discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.verifyIDToken(context.Background(), discovery, forged); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("forged token: err = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := issuer.provider()
+	if _, err := issuer.exchange(p); err != nil {
+		t.Fatal(err)
+	}
+
+	// Провайдер сменил ключ на Ed25519
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.signer, issuer.method, issuer.kid = private, jwt.SigningMethodEdDSA, "ed-2"
+
+	// Незнакомый kid вскоре после загрузки ключей не приводит к повторному запросу
+	if _, err := issuer.exchange(p); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken before refresh interval", err)
+	}
+	if got := issuer.jwksRequests.Load(); got != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", got)
+	}
+
+	p.mu.Lock()
+	p.keysAt = time.Now().Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+	if _, err := issuer.exchange(p); err != nil {
+		t.Fatalf("exchange after key rotation: %v", err)
+	}
+	if got := issuer.jwksRequests.Load(); got != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", got)
+	}
+}
+
+func TestOIDCDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	p := NewOIDCProvider(Config{Name: "test", ClientID: "client-id"}, issuer.server.URL+"/tenant")
+
+	// Документ discovery запрашивается по {issuer}/.well-known/..., которого у тестового сервера нет
+	if _, err := p.AuthCodeURL(context.Background(), AuthRequest{State: "s", CodeVerifier: "v"}); err == nil {
+		t.Fatal("discovery for another issuer succeeded")
+	}
+
+	// Неудачная попытка не кэшируется
+	p.issuer = issuer.server.URL
+	if _, err := p.AuthCodeURL(context.Background(), AuthRequest{State: "s", CodeVerifier: "v"}); err != nil {
+		t.Fatalf("discovery after failure: %v", err)
+	}
+}
+
+func TestJSONWebKeyPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, public := range map[string]crypto.PublicKey{"RSA": &rsaKey.PublicKey, "EC": &ecKey.PublicKey, "OKP": edPublic} {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := json.Marshal(publicJWK(t, "k", public))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var jwk jsonWebKey
+			if err := json.Unmarshal(encoded, &jwk); err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := jwk.publicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsed.(interface{ Equal(crypto.PublicKey) bool }).Equal(public) {
+				t.Fatalf("parsed key %v does not match %v", parsed, public)
+			}
+		})
+	}
+
+	invalid := map[string]jsonWebKey{
+		"unsupported type":      {Kty: "oct"},
+		"unsupported EC curve":  {Kty: "EC", Crv: "P-192", X: "AA", Y: "AA"},
+		"short EC coordinates":  {Kty: "EC", Crv: "P-256", X: "AAAA", Y: "AAAA"},
+		"unsupported OKP curve": {Kty: "OKP", Crv: "X25519", X: base64.RawURLEncoding.EncodeToString(make([]byte, 32))},
+		"short OKP key":         {Kty: "OKP", Crv: "Ed25519", X: "AAAA"},
+		"invalid RSA modulus":   {Kty: "RSA", N: "!!", E: "AQAB"},
+	}
+	for name, jwk := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := jwk.publicKey(); err == nil {
+				t.Fatal("invalid key was accepted")
+			}
+		})
+	}
+}
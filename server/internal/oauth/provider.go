@@ -0,0 +1,72 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+	ErrInvalidIDToken  = errors.New("invalid id_token")
+	ErrNonceMismatch   = errors.New("id_token nonce mismatch")
+)
+
+// Config - общие настройки OAuth клиента, выданные провайдером
+type Config struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// AuthRequest - параметры одного входа: state, PKCE verifier и nonce,
+// сохранённые между адресом авторизации и callback
+type AuthRequest struct {
+	State        string
+	CodeVerifier string
+	Nonce        string
+}
+
+// Identity - пользователь, каким его видит провайдер
+type Identity struct {
+	// Subject - постоянный идентификатор пользователя у провайдера
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	AvatarURL     string
+}
+
+// OAuthProvider - интерфейс провайдера входа
+type OAuthProvider interface {
+	// Name возвращает идентификатор провайдера, который используется в URL
+	Name() string
+	// UsesNonce сообщает, проверяет ли провайдер nonce в id_token
+	UsesNonce() bool
+	AuthCodeURL(ctx context.Context, req AuthRequest) (string, error)
+	// Exchange обменивает код авторизации на сведения о пользователе
+	Exchange(ctx context.Context, code string, req AuthRequest) (*Identity, error)
+}
+
+// httpClient - клиент для запросов к провайдерам: без таймаута зависший
+// провайдер держал бы запрос пользователя бесконечно
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+func withHTTPClient(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+}
+
+func authCodeOptions(req AuthRequest) []oauth2.AuthCodeOption {
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(req.CodeVerifier)}
+	if req.Nonce != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", req.Nonce))
+	}
+	return opts
+}
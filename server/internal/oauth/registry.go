@@ -0,0 +1,37 @@
+package oauth
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Registry хранит настроенных провайдеров по имени
+type Registry struct {
+	providers map[string]OAuthProvider
+}
+
+func NewRegistry(providers ...OAuthProvider) *Registry {
+	registry := &Registry{providers: make(map[string]OAuthProvider, len(providers))}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+func (r *Registry) Get(name string) (OAuthProvider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+// Names возвращает имена провайдеров в алфавитном порядке
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,217 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+const (
+	// FakeProviderName - имя локального платёжного провайдера
+	FakeProviderName = "fake"
+
+	// Способы оплаты, которые понимает FakeProvider
+	FakeCardSuccess  = "fake_card_success"
+	FakeCardDeclined = "fake_card_declined"
+)
+
+// FakeProvider - детерминированная платёжная система, работающая в памяти процесса.
+// Идентификаторы формируются из префикса экземпляра и счётчика, поэтому одинаковая
+// последовательность вызовов даёт одинаковый результат. Подходит для локальной
+// разработки и сквозного тестирования оформления заказа без доступа к сети.
+type FakeProvider struct {
+	webhookSecret []byte
+	// instanceID отделяет идентификаторы разных запусков, чтобы они не совпадали с уже сохранёнными
+	instanceID string
+
+	mu       sync.Mutex
+	sequence int
+	intents  map[string]*Intent
+	// refunds - выполненные возвраты по ключу идемпотентности
+	refunds map[string]*Refund
+}
+
+type fakeWebhookPayload struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	PaymentID string    `json:"payment_id"`
+	Amount    int64     `json:"amount"`
+}
+
+func NewFakeProvider(webhookSecret, instanceID string) *FakeProvider {
+	return &FakeProvider{
+		webhookSecret: []byte(webhookSecret),
+		instanceID:    instanceID,
+		intents:       make(map[string]*Intent),
+		refunds:       make(map[string]*Refund),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextID("fake_pi")
+	intent := &Intent{
+		ID:           id,
+		Status:       IntentRequiresConfirmation,
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		ClientSecret: id + "_secret",
+	}
+	p.intents[id] = intent
+
+	copied := *intent
+	return &copied, nil
+}
+
+// Confirm успешно подтверждает платёж для FakeCardSuccess и отклоняет для любого другого способа оплаты
+func (p *FakeProvider) Confirm(ctx context.Context, intentID, paymentMethod string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+
+	if intent.Status == IntentRequiresConfirmation {
+		if paymentMethod == FakeCardSuccess {
+			intent.Status = IntentRequiresCapture
+		} else {
+			intent.Status = IntentFailed
+			intent.FailureReason = ErrPaymentDeclined.Error()
+		}
+	}
+
+	copied := *intent
+	return &copied, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+
+	switch intent.Status {
+	case IntentRequiresCapture:
+		intent.Status = IntentSucceeded
+	case IntentSucceeded:
+	default:
+		return nil, fmt.Errorf("cannot capture payment in status %s", intent.Status)
+	}
+
+	copied := *intent
+	return &copied, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, intentID string, amount int64, idempotencyKey string) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if refund, ok := p.refunds[idempotencyKey]; ok && idempotencyKey != "" {
+		copied := *refund
+		return &copied, nil
+	}
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	if intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("cannot refund payment in status %s", intent.Status)
+	}
+	if amount <= 0 || amount > intent.Amount {
+		amount = intent.Amount
+	}
+
+	intent.Status = IntentRefunded
+	refund := &Refund{
+		ID:       p.nextID("fake_re"),
+		IntentID: intent.ID,
+		Amount:   amount,
+	}
+	if idempotencyKey != "" {
+		p.refunds[idempotencyKey] = refund
+	}
+
+	copied := *refund
+	return &copied, nil
+}
+
+func (p *FakeProvider) SignatureHeader() string {
+	return "X-Fake-Signature"
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	if !hmac.Equal([]byte(signature), []byte(p.sign(payload))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var body fakeWebhookPayload
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook payload: %w", err)
+	}
+
+	return &WebhookEvent{
+		ID:       body.ID,
+		Type:     body.Type,
+		IntentID: body.PaymentID,
+		Amount:   body.Amount,
+	}, nil
+}
+
+// NewWebhook формирует подписанный вебхук так, как его отправила бы платёжная система
+func (p *FakeProvider) NewWebhook(eventType EventType, intentID string) ([]byte, string, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	var amount int64
+	if ok {
+		amount = intent.Amount
+	}
+	eventID := p.nextID("fake_evt")
+	p.mu.Unlock()
+
+	if !ok {
+		return nil, "", ErrIntentNotFound
+	}
+
+	payload, err := json.Marshal(fakeWebhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		PaymentID: intentID,
+		Amount:    amount,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, p.sign(payload), nil
+}
+
+func (p *FakeProvider) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, p.webhookSecret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// nextID должен вызываться под мьютексом
+func (p *FakeProvider) nextID(prefix string) string {
+	p.sequence++
+	if p.instanceID == "" {
+		return fmt.Sprintf("%s_%06d", prefix, p.sequence)
+	}
+	return fmt.Sprintf("%s_%s_%06d", prefix, p.instanceID, p.sequence)
+}
@@ -0,0 +1,70 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func succeededIntent(t *testing.T, p *FakeProvider) *Intent {
+	t.Helper()
+
+	ctx := context.Background()
+	intent, err := p.CreateIntent(ctx, IntentRequest{OrderID: 1, Amount: 1000, Currency: "RUB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Confirm(ctx, intent.ID, FakeCardSuccess); err != nil {
+		t.Fatal(err)
+	}
+	if intent, err = p.Capture(ctx, intent.ID); err != nil {
+		t.Fatal(err)
+	}
+	return intent
+}
+
+func TestFakeProviderRefundIsIdempotent(t *testing.T) {
+	p := NewFakeProvider("secret", "")
+	intent := succeededIntent(t, p)
+
+	first, err := p.Refund(context.Background(), intent.ID, intent.Amount, "refund-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Refund(context.Background(), intent.ID, intent.Amount, "refund-1")
+	if err != nil {
+		t.Fatalf("retry with the same key: %v", err)
+	}
+	if first.ID != second.ID {
+		t.Fatalf("retry created a new refund %s, want %s", second.ID, first.ID)
+	}
+
+	if _, err := p.Refund(context.Background(), intent.ID, intent.Amount, "refund-2"); err == nil {
+		t.Fatal("second refund with a different key succeeded")
+	}
+}
+
+func TestFakeProviderWebhookSignature(t *testing.T) {
+	p := NewFakeProvider("secret", "")
+	intent := succeededIntent(t, p)
+
+	payload, signature, err := p.NewWebhook(EventPaymentSucceeded, intent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := p.ParseWebhook(payload, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != EventPaymentSucceeded || event.IntentID != intent.ID || event.Amount != intent.Amount {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	if _, err := NewFakeProvider("other", "").ParseWebhook(payload, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("webhook signed with another secret: err = %v, want ErrInvalidSignature", err)
+	}
+	if _, _, err := p.NewWebhook(EventPaymentSucceeded, "missing"); !errors.Is(err, ErrIntentNotFound) {
+		t.Fatalf("webhook for unknown intent: err = %v, want ErrIntentNotFound", err)
+	}
+}
@@ -0,0 +1,77 @@
+package payments
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrIntentNotFound   = errors.New("payment intent not found")
+	ErrPaymentDeclined  = errors.New("payment declined")
+)
+
+type IntentStatus string
+
+const (
+	IntentRequiresConfirmation IntentStatus = "requires_confirmation"
+	IntentRequiresCapture      IntentStatus = "requires_capture"
+	IntentSucceeded            IntentStatus = "succeeded"
+	IntentFailed               IntentStatus = "failed"
+	IntentRefunded             IntentStatus = "refunded"
+)
+
+type EventType string
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+	EventPaymentRefunded  EventType = "payment.refunded"
+)
+
+// IntentRequest описывает платёж по заказу. Суммы передаются в минимальных единицах валюты (копейках).
+type IntentRequest struct {
+	OrderID  int
+	Amount   int64
+	Currency string
+}
+
+type Intent struct {
+	ID            string
+	Status        IntentStatus
+	Amount        int64
+	Currency      string
+	ClientSecret  string
+	FailureReason string
+}
+
+type Refund struct {
+	ID       string
+	IntentID string
+	Amount   int64
+}
+
+// WebhookEvent - событие платёжной системы с проверенной подписью
+type WebhookEvent struct {
+	ID       string
+	Type     EventType
+	IntentID string
+	Amount   int64
+}
+
+// PaymentProvider - интерфейс платёжной системы
+type PaymentProvider interface {
+	// Name возвращает идентификатор провайдера, который используется в URL вебхука
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Confirm подтверждает платёж выбранным способом оплаты
+	Confirm(ctx context.Context, intentID, paymentMethod string) (*Intent, error)
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Refund возвращает деньги по платежу. Повторный вызов с тем же idempotencyKey
+	// не создаёт второй возврат, а возвращает уже выполненный.
+	Refund(ctx context.Context, intentID string, amount int64, idempotencyKey string) (*Refund, error)
+	// SignatureHeader возвращает имя заголовка, в котором провайдер передаёт подпись вебхука
+	SignatureHeader() string
+	// ParseWebhook проверяет подпись и разбирает тело вебхука
+	ParseWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
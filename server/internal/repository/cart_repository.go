@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"database/sql"
+
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const cartColumns = `id, created_at, updated_at, COALESCE(user_id, 0) AS user_id, COALESCE(guest_token, '') AS guest_token`
+
+type CartRepository struct {
+	db *sqlx.DB
+}
+
+func NewCartRepository(db *sqlx.DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+func (r *CartRepository) GetOrCreateByUserID(userID int) (*models.Cart, error) {
+	query := `INSERT INTO carts (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`
+	if _, err := r.db.Exec(query, userID); err != nil {
+		return nil, err
+	}
+
+	var cart models.Cart
+	query = `SELECT ` + cartColumns + ` FROM carts WHERE user_id = $1`
+	if err := r.db.Get(&cart, query, userID); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (r *CartRepository) GetOrCreateByGuestToken(guestToken string) (*models.Cart, error) {
+	query := `INSERT INTO carts (guest_token) VALUES ($1) ON CONFLICT (guest_token) DO NOTHING`
+	if _, err := r.db.Exec(query, guestToken); err != nil {
+		return nil, err
+	}
+
+	return r.GetByGuestToken(guestToken)
+}
+
+func (r *CartRepository) GetByGuestToken(guestToken string) (*models.Cart, error) {
+	var cart models.Cart
+	query := `SELECT ` + cartColumns + ` FROM carts WHERE guest_token = $1`
+	if err := r.db.Get(&cart, query, guestToken); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (r *CartRepository) GetItems(cartID int) ([]models.CartItem, error) {
+	var items []models.CartItem
+	query := `SELECT * FROM cart_items WHERE cart_id = $1 ORDER BY created_at, id`
+	err := r.db.Select(&items, query, cartID)
+	return items, err
+}
+
+func (r *CartRepository) GetLines(cartID int) ([]models.CartLine, error) {
+	lines := []models.CartLine{}
+	query := `
+		SELECT ci.id, ci.product_id, p.name AS product_name, ci.variant_id,
+			   COALESCE(v.sku, '') AS sku,
+			   COALESCE(concat_ws(' / ', NULLIF(v.size, ''), NULLIF(v.color, ''), NULLIF(v.material, '')), '') AS variant_name,
+			   COALESCE(v.image_urls[1], p.image_url, '') AS image_url,
+			   ci.quantity, COALESCE(v.price, p.price) AS unit_price, COALESCE(v.in_stock, p.in_stock) AND p.deleted_at IS NULL AS in_stock,
+			   ci.unit_price <> COALESCE(v.price, p.price) AS price_changed
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.created_at, ci.id`
+	err := r.db.Select(&lines, query, cartID)
+	return lines, err
+}
+
+func (r *CartRepository) GetItem(cartID, itemID int) (*models.CartItem, error) {
+	var item models.CartItem
+	query := `SELECT * FROM cart_items WHERE cart_id = $1 AND id = $2`
+	err := r.db.Get(&item, query, cartID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// AddItem добавляет товар в корзину или увеличивает количество уже добавленного
+func (r *CartRepository) AddItem(cartID, productID int, variantID *int, quantity int, unitPrice float64) error {
+	query := `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity, unit_price)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (cart_id, product_id, (COALESCE(variant_id, 0))) DO UPDATE
+		SET quantity = LEAST(cart_items.quantity + EXCLUDED.quantity, $6), unit_price = EXCLUDED.unit_price`
+	_, err := r.db.Exec(query, cartID, productID, variantID, quantity, unitPrice, models.MaxCartItemQuantity)
+	return err
+}
+
+func (r *CartRepository) UpdateItem(cartID, itemID, quantity int, unitPrice float64) error {
+	query := `UPDATE cart_items SET quantity = $3, unit_price = $4 WHERE cart_id = $1 AND id = $2`
+	return execAffectingRow(r.db, query, cartID, itemID, quantity, unitPrice)
+}
+
+func (r *CartRepository) RemoveItem(cartID, itemID int) error {
+	query := `DELETE FROM cart_items WHERE cart_id = $1 AND id = $2`
+	return execAffectingRow(r.db, query, cartID, itemID)
+}
+
+func (r *CartRepository) Clear(cartID int) error {
+	query := `DELETE FROM cart_items WHERE cart_id = $1`
+	_, err := r.db.Exec(query, cartID)
+	return err
+}
+
+// GetItemsForUpdate блокирует позиции корзины до конца транзакции
+func (r *CartRepository) GetItemsForUpdate(tx *sqlx.Tx, cartID int) ([]models.CartItem, error) {
+	var items []models.CartItem
+	query := `SELECT * FROM cart_items WHERE cart_id = $1 ORDER BY product_id, variant_id NULLS FIRST FOR UPDATE`
+	err := tx.Select(&items, query, cartID)
+	return items, err
+}
+
+func (r *CartRepository) ClearTx(tx *sqlx.Tx, cartID int) error {
+	query := `DELETE FROM cart_items WHERE cart_id = $1`
+	_, err := tx.Exec(query, cartID)
+	return err
+}
+
+// MergeGuestCart переносит позиции гостевой корзины в корзину пользователя и удаляет гостевую корзину.
+// Если товар уже есть в корзине пользователя, остаётся большее из двух количеств.
+func (r *CartRepository) MergeGuestCart(guestCartID, userCartID int, items []models.CartItem) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO cart_items (cart_id, product_id, variant_id, quantity, unit_price)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (cart_id, product_id, (COALESCE(variant_id, 0))) DO UPDATE
+		SET quantity = GREATEST(cart_items.quantity, EXCLUDED.quantity), unit_price = EXCLUDED.unit_price`
+	for _, item := range items {
+		if _, err := tx.Exec(query, userCartID, item.ProductID, item.VariantID, item.Quantity, item.UnitPrice); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM carts WHERE id = $1`, guestCartID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execAffectingRow выполняет запрос и возвращает sql.ErrNoRows, если ни одна строка не была затронута
+func execAffectingRow(db sqlx.Execer, query string, args ...interface{}) error {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
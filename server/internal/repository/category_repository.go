@@ -16,14 +16,14 @@ func NewCategoryRepository(db *sqlx.DB) *CategoryRepository {
 
 func (r *CategoryRepository) GetAll() ([]models.Category, error) {
 	var categories []models.Category
-	query := `SELECT * FROM categories ORDER BY name`
+	query := `SELECT * FROM categories WHERE deleted_at IS NULL ORDER BY name`
 	err := r.db.Select(&categories, query)
 	return categories, err
 }
 
 func (r *CategoryRepository) GetByID(id int) (*models.Category, error) {
 	var category models.Category
-	query := `SELECT * FROM categories WHERE id = $1`
+	query := `SELECT * FROM categories WHERE id = $1 AND deleted_at IS NULL`
 	err := r.db.Get(&category, query, id)
 	if err != nil {
 		return nil, err
@@ -36,3 +36,59 @@ func (r *CategoryRepository) Create(category *models.Category) error {
 	return r.db.QueryRow(query, category.Name, category.Description, category.ImageURL).
 		Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
 }
+
+func (r *CategoryRepository) GetByName(name string) (*models.Category, error) {
+	var category models.Category
+	query := `SELECT * FROM categories WHERE name = $1 AND deleted_at IS NULL`
+	err := r.db.Get(&category, query, name)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetAllWithDeleted возвращает категории вместе со скрытыми, для админки
+func (r *CategoryRepository) GetAllWithDeleted() ([]models.Category, error) {
+	categories := []models.Category{}
+	query := `SELECT * FROM categories ORDER BY name`
+	err := r.db.Select(&categories, query)
+	return categories, err
+}
+
+func (r *CategoryRepository) GetByIDForUpdate(tx *sqlx.Tx, id int) (*models.Category, error) {
+	var category models.Category
+	query := `SELECT * FROM categories WHERE id = $1 FOR UPDATE`
+	err := tx.Get(&category, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// Update изменяет категорию; товары привязаны к категории по имени,
+// поэтому при переименовании они переносятся в той же транзакции
+func (r *CategoryRepository) Update(tx *sqlx.Tx, category *models.Category, previousName string) error {
+	query := `
+		UPDATE categories SET name = $2, description = $3, image_url = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at`
+	err := tx.QueryRow(query, category.ID, category.Name, category.Description, category.ImageURL).
+		Scan(&category.UpdatedAt)
+	if err != nil || previousName == category.Name {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE products SET category = $2, updated_at = CURRENT_TIMESTAMP WHERE category = $1`,
+		previousName, category.Name)
+	return err
+}
+
+func (r *CategoryRepository) SoftDelete(id int) error {
+	query := `UPDATE categories SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`
+	return execAffectingRow(r.db, query, id)
+}
+
+func (r *CategoryRepository) Restore(id int) error {
+	query := `UPDATE categories SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL`
+	return execAffectingRow(r.db, query, id)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"time"
+
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// InventoryRepository управляет складскими остатками и резервами.
+// Все изменения остатков выполняются условными UPDATE, поэтому остаток
+// не может уйти в минус даже при параллельных заказах.
+type InventoryRepository struct {
+	db *sqlx.DB
+}
+
+func NewInventoryRepository(db *sqlx.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// Reserve удерживает quantity единиц товара или варианта; возвращает sql.ErrNoRows, если свободного остатка не хватает
+func (r *InventoryRepository) Reserve(tx *sqlx.Tx, reservation *models.StockReservation) error {
+	table, id := stockTarget(reservation)
+	query := `
+		UPDATE ` + table + ` SET reserved_quantity = reserved_quantity + $2
+		WHERE id = $1 AND stock_quantity - reserved_quantity >= $2`
+	if err := execAffectingRow(tx, query, id, reservation.Quantity); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO stock_reservations (order_id, product_id, variant_id, quantity, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+	return tx.QueryRow(query, reservation.OrderID, reservation.ProductID, reservation.VariantID, reservation.Quantity,
+		reservation.Status, reservation.ExpiresAt).
+		Scan(&reservation.ID, &reservation.CreatedAt, &reservation.UpdatedAt)
+}
+
+// GetByOrderForUpdate блокирует резервы заказа с указанным статусом до конца транзакции
+func (r *InventoryRepository) GetByOrderForUpdate(tx *sqlx.Tx, orderID int, status models.ReservationStatus) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	query := `SELECT * FROM stock_reservations WHERE order_id = $1 AND status = $2 ORDER BY product_id, variant_id NULLS FIRST FOR UPDATE`
+	err := tx.Select(&reservations, query, orderID, status)
+	return reservations, err
+}
+
+// Commit списывает зарезервированный товар со склада после оплаты
+func (r *InventoryRepository) Commit(tx *sqlx.Tx, reservation *models.StockReservation) error {
+	table, id := stockTarget(reservation)
+	query := `
+		UPDATE ` + table + `
+		SET stock_quantity = stock_quantity - $2, reserved_quantity = reserved_quantity - $2
+		WHERE id = $1`
+	if err := execAffectingRow(tx, query, id, reservation.Quantity); err != nil {
+		return err
+	}
+
+	return r.setStatus(tx, reservation.ID, models.ReservationCommitted)
+}
+
+// Release снимает резерв без списания товара
+func (r *InventoryRepository) Release(tx *sqlx.Tx, reservation *models.StockReservation) error {
+	table, id := stockTarget(reservation)
+	query := `UPDATE ` + table + ` SET reserved_quantity = reserved_quantity - $2 WHERE id = $1`
+	if err := execAffectingRow(tx, query, id, reservation.Quantity); err != nil {
+		return err
+	}
+
+	return r.setStatus(tx, reservation.ID, models.ReservationReleased)
+}
+
+// Restock возвращает списанный товар на склад
+func (r *InventoryRepository) Restock(tx *sqlx.Tx, reservation *models.StockReservation) error {
+	table, id := stockTarget(reservation)
+	query := `UPDATE ` + table + ` SET stock_quantity = stock_quantity + $2 WHERE id = $1`
+	if err := execAffectingRow(tx, query, id, reservation.Quantity); err != nil {
+		return err
+	}
+
+	return r.setStatus(tx, reservation.ID, models.ReservationRestocked)
+}
+
+// GetOrdersWithExpiredReservations возвращает заказы, у которых истёк срок активного резерва.
+// Заказы с ожидающим платежом, созданным после paymentsSince, пропускаются: покупатель ещё платит.
+func (r *InventoryRepository) GetOrdersWithExpiredReservations(now, paymentsSince time.Time, limit int) ([]int, error) {
+	var orderIDs []int
+	query := `
+		SELECT DISTINCT r.order_id FROM stock_reservations r
+		WHERE r.status = 'active' AND r.expires_at <= $1
+			AND NOT EXISTS (
+				SELECT 1 FROM payments p
+				WHERE p.order_id = r.order_id AND p.status = 'pending' AND p.created_at > $2
+			)
+		ORDER BY r.order_id
+		LIMIT $3`
+	err := r.db.Select(&orderIDs, query, now, paymentsSince, limit)
+	return orderIDs, err
+}
+
+func (r *InventoryRepository) setStatus(tx *sqlx.Tx, reservationID int, status models.ReservationStatus) error {
+	query := `UPDATE stock_reservations SET status = $2 WHERE id = $1`
+	_, err := tx.Exec(query, reservationID, status)
+	return err
+}
+
+// stockTarget возвращает таблицу и строку, в которой хранится остаток резерва:
+// для вариантов остаток ведётся по варианту, а остаток товара пересчитывается триггером
+func stockTarget(reservation *models.StockReservation) (string, int) {
+	if reservation.VariantID != nil {
+		return "product_variants", *reservation.VariantID
+	}
+	return "products", reservation.ProductID
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LoginAttemptRepository хранит счётчики неудачных входов в Postgres,
+// поэтому блокировки общие для всех экземпляров приложения
+type LoginAttemptRepository struct {
+	db *sqlx.DB
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Get(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.Get(&attempt, `SELECT * FROM login_attempts WHERE key = $1`, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RegisterFailure атомарно увеличивает счётчик. Если последняя неудача была
+// раньше resetBefore, счёт начинается заново.
+func (r *LoginAttemptRepository) RegisterFailure(key string, now, resetBefore time.Time) (*models.LoginAttempt, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING *`
+
+	var attempt models.LoginAttempt
+	if err := r.db.Get(&attempt, query, key, now, resetBefore); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *LoginAttemptRepository) Lock(key string, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE login_attempts SET locked_until = $2 WHERE key = $1`, key, until)
+	return err
+}
+
+func (r *LoginAttemptRepository) Reset(key string) error {
+	_, err := r.db.Exec(`DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
+
+// DeleteStale удаляет счётчики без свежих неудач и без действующей блокировки
+func (r *LoginAttemptRepository) DeleteStale(before time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_attempts
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $1)`
+	result, err := r.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
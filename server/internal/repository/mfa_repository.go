@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type MFARepository struct {
+	db *sqlx.DB
+}
+
+func NewMFARepository(db *sqlx.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+func (r *MFARepository) GetTOTP(userID int) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+	err := r.db.Get(&totp, `SELECT * FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+func (r *MFARepository) GetTOTPForUpdate(tx *sqlx.Tx, userID int) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+	err := tx.Get(&totp, `SELECT * FROM user_totp WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// SaveTOTPSecret сохраняет новый неподтверждённый секрет вместо прежнего
+func (r *MFARepository) SaveTOTPSecret(userID int, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = CURRENT_TIMESTAMP, enabled_at = NULL, last_used_step = 0`
+	_, err := r.db.Exec(query, userID, secret)
+	return err
+}
+
+func (r *MFARepository) EnableTOTP(tx *sqlx.Tx, userID int, step int64) error {
+	query := `UPDATE user_totp SET enabled_at = CURRENT_TIMESTAMP, last_used_step = $2 WHERE user_id = $1`
+	return execAffectingRow(tx, query, userID, step)
+}
+
+func (r *MFARepository) SetLastUsedStep(tx *sqlx.Tx, userID int, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1`
+	return execAffectingRow(tx, query, userID, step)
+}
+
+// DeleteTOTP отключает TOTP и удаляет коды восстановления
+func (r *MFARepository) DeleteTOTP(tx *sqlx.Tx, userID int) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM user_totp WHERE user_id = $1`, userID)
+	return err
+}
+
+// ReplaceRecoveryCodes удаляет прежние коды восстановления и сохраняет новые
+func (r *MFARepository) ReplaceRecoveryCodes(tx *sqlx.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode помечает код использованным; sql.ErrNoRows, если кода нет или он уже использован
+func (r *MFARepository) UseRecoveryCode(tx *sqlx.Tx, userID int, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	return execAffectingRow(tx, query, userID, codeHash)
+}
+
+func (r *MFARepository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID)
+	return count, err
+}
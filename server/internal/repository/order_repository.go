@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type OrderRepository struct {
+	db *sqlx.DB
+}
+
+func NewOrderRepository(db *sqlx.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func (r *OrderRepository) Create(tx *sqlx.Tx, order *models.Order) error {
+	query := `
+		INSERT INTO orders (user_id, status, total, shipping_address, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	return tx.QueryRow(query, order.UserID, order.Status, order.Total, order.ShippingAddress, order.Comment).
+		Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+}
+
+func (r *OrderRepository) AddItem(tx *sqlx.Tx, item *models.OrderItem) error {
+	query := `
+		INSERT INTO order_items (order_id, product_id, variant_id, product_name, sku, variant_name, unit_price, quantity, line_total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+	return tx.QueryRow(query, item.OrderID, item.ProductID, item.VariantID, item.ProductName, item.SKU, item.VariantName,
+		item.UnitPrice, item.Quantity, item.LineTotal).
+		Scan(&item.ID)
+}
+
+func (r *OrderRepository) AddStatusChange(tx *sqlx.Tx, change *models.OrderStatusChange) error {
+	query := `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	return tx.QueryRow(query, change.OrderID, change.FromStatus, change.ToStatus, change.ChangedBy, change.Note).
+		Scan(&change.ID, &change.CreatedAt)
+}
+
+func (r *OrderRepository) GetByID(id int) (*models.Order, error) {
+	var order models.Order
+	query := `SELECT * FROM orders WHERE id = $1`
+	err := r.db.Get(&order, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetByIDForUpdate блокирует строку заказа до конца транзакции
+func (r *OrderRepository) GetByIDForUpdate(tx *sqlx.Tx, id int) (*models.Order, error) {
+	var order models.Order
+	query := `SELECT * FROM orders WHERE id = $1 FOR UPDATE`
+	err := tx.Get(&order, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *OrderRepository) GetByUserID(userID, limit, offset int) ([]models.Order, error) {
+	orders := []models.Order{}
+	query := `SELECT * FROM orders WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`
+	err := r.db.Select(&orders, query, userID, limit, offset)
+	return orders, err
+}
+
+func (r *OrderRepository) CountByUserID(userID int) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM orders WHERE user_id = $1`
+	err := r.db.Get(&count, query, userID)
+	return count, err
+}
+
+func (r *OrderRepository) GetItems(orderID int) ([]models.OrderItem, error) {
+	var items []models.OrderItem
+	query := `SELECT * FROM order_items WHERE order_id = $1 ORDER BY id`
+	err := r.db.Select(&items, query, orderID)
+	return items, err
+}
+
+func (r *OrderRepository) GetHistory(orderID int) ([]models.OrderStatusChange, error) {
+	var history []models.OrderStatusChange
+	query := `SELECT * FROM order_status_history WHERE order_id = $1 ORDER BY created_at, id`
+	err := r.db.Select(&history, query, orderID)
+	return history, err
+}
+
+func (r *OrderRepository) UpdateStatus(tx *sqlx.Tx, orderID int, status models.OrderStatus) error {
+	query := `UPDATE orders SET status = $2 WHERE id = $1`
+	_, err := tx.Exec(query, orderID, status)
+	return err
+}
+
+// HasDeliveredProduct проверяет, получал ли пользователь товар в доставленном заказе
+func (r *OrderRepository) HasDeliveredProduct(userID, productID int) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.user_id = $1 AND oi.product_id = $2 AND o.status = 'delivered'
+		)`
+	err := r.db.Get(&exists, query, userID, productID)
+	return exists, err
+}
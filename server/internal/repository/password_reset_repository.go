@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PasswordResetRepository struct {
+	db *sqlx.DB
+}
+
+func NewPasswordResetRepository(db *sqlx.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create сохраняет новый токен; прежние неиспользованные токены пользователя
+// перестают действовать, чтобы работала только ссылка из последнего письма
+func (r *PasswordResetRepository) Create(tx *sqlx.Tx, token *models.PasswordResetToken) error {
+	_, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+	return tx.QueryRow(query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *PasswordResetRepository) GetByHashForUpdate(tx *sqlx.Tx, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	query := `SELECT * FROM password_reset_tokens WHERE token_hash = $1 FOR UPDATE`
+	err := tx.Get(&token, query, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PasswordResetRepository) MarkUsed(tx *sqlx.Tx, id int) error {
+	query := `UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`
+	return execAffectingRow(tx, query, id)
+}
+
+func (r *PasswordResetRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM password_reset_tokens WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
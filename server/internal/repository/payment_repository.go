@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PaymentRepository struct {
+	db *sqlx.DB
+}
+
+func NewPaymentRepository(db *sqlx.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+func (r *PaymentRepository) Create(payment *models.Payment) error {
+	query := `
+		INSERT INTO payments (order_id, provider, provider_payment_id, status, amount, currency, failure_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+	return r.db.QueryRow(query, payment.OrderID, payment.Provider, payment.ProviderPaymentID, payment.Status,
+		payment.Amount, payment.Currency, payment.FailureReason).
+		Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+}
+
+// GetLatestByOrderID возвращает последний платёж по заказу
+func (r *PaymentRepository) GetLatestByOrderID(orderID int) (*models.Payment, error) {
+	var payment models.Payment
+	query := `SELECT * FROM payments WHERE order_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`
+	err := r.db.Get(&payment, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetByProviderIDForUpdate блокирует платёж до конца транзакции
+func (r *PaymentRepository) GetByProviderIDForUpdate(tx *sqlx.Tx, provider, providerPaymentID string) (*models.Payment, error) {
+	var payment models.Payment
+	query := `SELECT * FROM payments WHERE provider = $1 AND provider_payment_id = $2 FOR UPDATE`
+	err := tx.Get(&payment, query, provider, providerPaymentID)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetByStatus возвращает самые старые платежи в статусе status
+func (r *PaymentRepository) GetByStatus(status models.PaymentStatus, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	query := `SELECT * FROM payments WHERE status = $1 ORDER BY updated_at, id LIMIT $2`
+	err := r.db.Select(&payments, query, status, limit)
+	return payments, err
+}
+
+func (r *PaymentRepository) UpdateStatus(tx *sqlx.Tx, paymentID int, status models.PaymentStatus, failureReason string) error {
+	query := `UPDATE payments SET status = $2, failure_reason = $3 WHERE id = $1`
+	_, err := tx.Exec(query, paymentID, status, failureReason)
+	return err
+}
+
+// RecordWebhookEvent сохраняет событие вебхука и возвращает false, если оно уже было обработано
+func (r *PaymentRepository) RecordWebhookEvent(tx *sqlx.Tx, provider, eventID, eventType string) (bool, error) {
+	query := `
+		INSERT INTO payment_webhook_events (provider, event_id, event_type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (provider, event_id) DO NOTHING`
+	result, err := tx.Exec(query, provider, eventID, eventType)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -16,31 +16,111 @@ func NewProductRepository(db *sqlx.DB) *ProductRepository {
 
 func (r *ProductRepository) GetAll(limit, offset int) ([]models.Product, error) {
 	var products []models.Product
-	query := `SELECT * FROM products ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+	query := `SELECT * FROM products WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT $1 OFFSET $2`
 	err := r.db.Select(&products, query, limit, offset)
 	return products, err
 }
 
+// GetByID возвращает товар вместе с его вариантами
 func (r *ProductRepository) GetByID(id int) (*models.Product, error) {
 	var product models.Product
-	query := `SELECT * FROM products WHERE id = $1`
+	query := `SELECT * FROM products WHERE id = $1 AND deleted_at IS NULL`
 	err := r.db.Get(&product, query, id)
 	if err != nil {
 		return nil, err
 	}
+
+	product.Variants, err = r.GetVariants(id)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *ProductRepository) GetVariants(productID int) ([]models.ProductVariant, error) {
+	var variants []models.ProductVariant
+	query := `SELECT * FROM product_variants WHERE product_id = $1 ORDER BY id`
+	err := r.db.Select(&variants, query, productID)
+	return variants, err
+}
+
+func (r *ProductRepository) GetVariant(productID, variantID int) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	query := `SELECT * FROM product_variants WHERE product_id = $1 AND id = $2`
+	err := r.db.Get(&variant, query, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *ProductRepository) HasVariants(tx *sqlx.Tx, productID int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1)`
+	err := tx.Get(&exists, query, productID)
+	return exists, err
+}
+
+// GetVariantForUpdate блокирует строку варианта до конца транзакции
+func (r *ProductRepository) GetVariantForUpdate(tx *sqlx.Tx, productID, variantID int) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	query := `SELECT * FROM product_variants WHERE product_id = $1 AND id = $2 FOR UPDATE`
+	err := tx.Get(&variant, query, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *ProductRepository) CreateVariant(tx *sqlx.Tx, variant *models.ProductVariant) error {
+	query := `
+		INSERT INTO product_variants (product_id, sku, size, color, material, price, stock_quantity, image_urls)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at, in_stock`
+	return tx.QueryRow(query, variant.ProductID, variant.SKU, variant.Size, variant.Color, variant.Material,
+		variant.Price, variant.StockQuantity, variant.ImageURLs).
+		Scan(&variant.ID, &variant.CreatedAt, &variant.UpdatedAt, &variant.InStock)
+}
+
+func (r *ProductRepository) UpdateVariant(tx *sqlx.Tx, variant *models.ProductVariant) error {
+	query := `
+		UPDATE product_variants
+		SET sku = $2, size = $3, color = $4, material = $5, price = $6, stock_quantity = $7, image_urls = $8,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at, in_stock`
+	return tx.QueryRow(query, variant.ID, variant.SKU, variant.Size, variant.Color, variant.Material,
+		variant.Price, variant.StockQuantity, variant.ImageURLs).
+		Scan(&variant.UpdatedAt, &variant.InStock)
+}
+
+func (r *ProductRepository) DeleteVariant(tx *sqlx.Tx, variantID int) error {
+	query := `DELETE FROM product_variants WHERE id = $1`
+	_, err := tx.Exec(query, variantID)
+	return err
+}
+
+// GetByIDForUpdate блокирует строку товара до конца транзакции
+func (r *ProductRepository) GetByIDForUpdate(tx *sqlx.Tx, id int) (*models.Product, error) {
+	var product models.Product
+	query := `SELECT * FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	err := tx.Get(&product, query, id)
+	if err != nil {
+		return nil, err
+	}
 	return &product, nil
 }
 
 func (r *ProductRepository) GetByCategory(category string, limit, offset int) ([]models.Product, error) {
 	var products []models.Product
-	query := `SELECT * FROM products WHERE category = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	query := `SELECT * FROM products WHERE category = $1 AND deleted_at IS NULL ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 	err := r.db.Select(&products, query, category, limit, offset)
 	return products, err
 }
 
 func (r *ProductRepository) GetFeatured(limit int) ([]models.Product, error) {
 	var products []models.Product
-	query := `SELECT * FROM products WHERE in_stock = true ORDER BY rating DESC, views DESC LIMIT $1`
+	query := `SELECT * FROM products WHERE in_stock = true AND deleted_at IS NULL ORDER BY rating DESC, review_count DESC, views DESC LIMIT $1`
 	err := r.db.Select(&products, query, limit)
 	return products, err
 }
@@ -48,7 +128,7 @@ func (r *ProductRepository) GetFeatured(limit int) ([]models.Product, error) {
 func (r *ProductRepository) Search(query string, limit, offset int) ([]models.Product, error) {
 	var products []models.Product
 	searchPattern := "%" + query + "%"
-	sqlQuery := `SELECT * FROM products WHERE name ILIKE $1 OR description ILIKE $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	sqlQuery := `SELECT * FROM products WHERE (name ILIKE $1 OR description ILIKE $1) AND deleted_at IS NULL ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 	err := r.db.Select(&products, sqlQuery, searchPattern, limit, offset)
 	return products, err
 }
@@ -60,8 +140,91 @@ func (r *ProductRepository) IncrementViews(id int) error {
 }
 
 func (r *ProductRepository) Count() (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM products WHERE deleted_at IS NULL`
+	err := r.db.Get(&count, query)
+	return count, err
+}
+
+// GetAllWithDeleted возвращает товары вместе со скрытыми, для админки
+func (r *ProductRepository) GetAllWithDeleted(limit, offset int) ([]models.Product, error) {
+	var products []models.Product
+	query := `SELECT * FROM products ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`
+	err := r.db.Select(&products, query, limit, offset)
+	return products, err
+}
+
+func (r *ProductRepository) CountWithDeleted() (int64, error) {
 	var count int64
 	query := `SELECT COUNT(*) FROM products`
 	err := r.db.Get(&count, query)
 	return count, err
 }
+
+// GetByIDWithDeleted возвращает товар, в том числе скрытый, без вариантов
+func (r *ProductRepository) GetByIDWithDeleted(id int) (*models.Product, error) {
+	var product models.Product
+	query := `SELECT * FROM products WHERE id = $1`
+	err := r.db.Get(&product, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetByIDWithDeletedForUpdate блокирует строку товара, в том числе скрытого
+func (r *ProductRepository) GetByIDWithDeletedForUpdate(tx *sqlx.Tx, id int) (*models.Product, error) {
+	var product models.Product
+	query := `SELECT * FROM products WHERE id = $1 FOR UPDATE`
+	err := tx.Get(&product, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *ProductRepository) Create(product *models.Product) error {
+	query := `
+		INSERT INTO products (name, description, price, image_url, category, stock_quantity)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at, in_stock`
+	return r.db.QueryRow(query, product.Name, product.Description, product.Price, product.ImageURL,
+		product.Category, product.StockQuantity).
+		Scan(&product.ID, &product.CreatedAt, &product.UpdatedAt, &product.InStock)
+}
+
+// Update изменяет карточку товара. Остаток товара с вариантами считается триггером
+// по вариантам, поэтому для таких товаров stock_quantity не меняется.
+func (r *ProductRepository) Update(tx *sqlx.Tx, product *models.Product) error {
+	query := `
+		UPDATE products
+		SET name = $2, description = $3, price = $4, image_url = $5, category = $6,
+			stock_quantity = CASE
+				WHEN EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1) THEN stock_quantity
+				ELSE $7
+			END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at, stock_quantity, in_stock`
+	return tx.QueryRow(query, product.ID, product.Name, product.Description, product.Price, product.ImageURL,
+		product.Category, product.StockQuantity).
+		Scan(&product.UpdatedAt, &product.StockQuantity, &product.InStock)
+}
+
+// SoftDelete скрывает товар из каталога; заказы и отзывы на него сохраняются
+func (r *ProductRepository) SoftDelete(id int) error {
+	query := `UPDATE products SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`
+	return execAffectingRow(r.db, query, id)
+}
+
+func (r *ProductRepository) Restore(id int) error {
+	query := `UPDATE products SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL`
+	return execAffectingRow(r.db, query, id)
+}
+
+func (r *ProductRepository) CountByCategory(category string) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM products WHERE category = $1 AND deleted_at IS NULL`
+	err := r.db.Get(&count, query, category)
+	return count, err
+}
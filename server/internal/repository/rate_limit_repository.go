@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RateLimitRepository хранит корзины токенов в Postgres, чтобы лимиты
+// были общими для нескольких экземпляров приложения
+type RateLimitRepository struct {
+	db *sqlx.DB
+}
+
+func NewRateLimitRepository(db *sqlx.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Take забирает токен из корзины key; строка корзины блокируется на время расчёта
+func (r *RateLimitRepository) Take(key string, rate, burst float64, now time.Time) (*models.RateLimitBucket, bool, error) {
+	var (
+		bucket  models.RateLimitBucket
+		allowed bool
+	)
+
+	err := NewTxManager(r.db).WithTx(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (key) DO NOTHING`, key, burst, now)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Get(&bucket, `SELECT * FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`, key); err != nil {
+			return err
+		}
+
+		allowed = bucket.Take(now, rate, burst)
+
+		_, err = tx.Exec(`UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1`,
+			key, bucket.Tokens, bucket.UpdatedAt)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &bucket, allowed, nil
+}
+
+// DeleteStale удаляет корзины, к которым давно не обращались: они уже полные
+func (r *RateLimitRepository) DeleteStale(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RefreshTokenRepository struct {
+	db *sqlx.DB
+}
+
+func NewRefreshTokenRepository(db *sqlx.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) Create(tx *sqlx.Tx, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	return tx.QueryRow(query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetByHashForUpdate блокирует запись токена, чтобы один токен нельзя было обменять дважды параллельно
+func (r *RefreshTokenRepository) GetByHashForUpdate(tx *sqlx.Tx, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	query := `SELECT * FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`
+	err := tx.Get(&token, query, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *RefreshTokenRepository) MarkUsed(tx *sqlx.Tx, id int) error {
+	query := `UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`
+	return execAffectingRow(tx, query, id)
+}
+
+// RevokeFamily отзывает все токены семейства
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.Exec(query, familyID)
+	return err
+}
+
+// RevokeFamilyByTokenHash отзывает семейство, к которому относится токен
+func (r *RefreshTokenRepository) RevokeFamilyByTokenHash(tokenHash string) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1) AND revoked_at IS NULL`
+	_, err := r.db.Exec(query, tokenHash)
+	return err
+}
+
+// RevokeAllForUser отзывает все refresh токены пользователя
+func (r *RefreshTokenRepository) RevokeAllForUser(userID int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
+
+// DeleteExpired удаляет токены, срок действия которых истёк
+func (r *RefreshTokenRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM refresh_tokens WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
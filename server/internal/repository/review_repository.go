@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ReviewRepository struct {
+	db *sqlx.DB
+}
+
+func NewReviewRepository(db *sqlx.DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+func (r *ReviewRepository) Create(tx *sqlx.Tx, review *models.Review) error {
+	query := `
+		INSERT INTO reviews (product_id, user_id, rating, title, body)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	return tx.QueryRow(query, review.ProductID, review.UserID, review.Rating, review.Title, review.Body).
+		Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
+}
+
+func (r *ReviewRepository) Update(tx *sqlx.Tx, review *models.Review) error {
+	query := `
+		UPDATE reviews SET rating = $2, title = $3, body = $4
+		WHERE id = $1
+		RETURNING updated_at`
+	return tx.QueryRow(query, review.ID, review.Rating, review.Title, review.Body).Scan(&review.UpdatedAt)
+}
+
+func (r *ReviewRepository) Delete(tx *sqlx.Tx, id int) error {
+	query := `DELETE FROM reviews WHERE id = $1`
+	return execAffectingRow(tx, query, id)
+}
+
+func (r *ReviewRepository) GetByID(id int) (*models.Review, error) {
+	var review models.Review
+	query := `SELECT * FROM reviews WHERE id = $1`
+	err := r.db.Get(&review, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *ReviewRepository) ExistsForUser(tx *sqlx.Tx, productID, userID int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM reviews WHERE product_id = $1 AND user_id = $2)`
+	err := tx.Get(&exists, query, productID, userID)
+	return exists, err
+}
+
+func (r *ReviewRepository) GetByProductID(productID, limit, offset int) ([]models.Review, error) {
+	reviews := []models.Review{}
+	query := `
+		SELECT r.*, TRIM(u.first_name || ' ' || LEFT(u.last_name, 1)) AS author_name
+		FROM reviews r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.product_id = $1
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT $2 OFFSET $3`
+	err := r.db.Select(&reviews, query, productID, limit, offset)
+	return reviews, err
+}
+
+func (r *ReviewRepository) GetByUserID(userID int) ([]models.Review, error) {
+	reviews := []models.Review{}
+	query := `SELECT * FROM reviews WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	err := r.db.Select(&reviews, query, userID)
+	return reviews, err
+}
+
+func (r *ReviewRepository) CountByProductID(productID int) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM reviews WHERE product_id = $1`
+	err := r.db.Get(&count, query, productID)
+	return count, err
+}
+
+// RecalculateProductRating пересчитывает средний рейтинг и число отзывов товара
+func (r *ReviewRepository) RecalculateProductRating(tx *sqlx.Tx, productID int) error {
+	query := `
+		UPDATE products SET
+			rating = COALESCE((SELECT ROUND(AVG(rating)::numeric, 2) FROM reviews WHERE product_id = $1), 0),
+			review_count = (SELECT COUNT(*) FROM reviews WHERE product_id = $1)
+		WHERE id = $1`
+	_, err := tx.Exec(query, productID)
+	return err
+}
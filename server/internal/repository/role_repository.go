@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RoleRepository struct {
+	db *sqlx.DB
+}
+
+func NewRoleRepository(db *sqlx.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// GetPermissionsByRole возвращает права каждой роли
+func (r *RoleRepository) GetPermissionsByRole() (map[string][]string, error) {
+	var rows []struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}
+	query := `SELECT role, permission FROM role_permissions ORDER BY role, permission`
+	if err := r.db.Select(&rows, query); err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string][]string)
+	for _, row := range rows {
+		permissions[row.Role] = append(permissions[row.Role], row.Permission)
+	}
+	return permissions, nil
+}
+
+func (r *RoleRepository) GetUserRoles(userID int) ([]models.UserRole, error) {
+	roles := []models.UserRole{}
+	query := `SELECT * FROM user_roles WHERE user_id = $1 ORDER BY role`
+	err := r.db.Select(&roles, query, userID)
+	return roles, err
+}
+
+// Grant назначает роль; повторное назначение ничего не меняет
+func (r *RoleRepository) Grant(userID int, role string, grantedBy *int) error {
+	query := `
+		INSERT INTO user_roles (user_id, role, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, role) DO NOTHING`
+	_, err := r.db.Exec(query, userID, role, grantedBy)
+	return err
+}
+
+func (r *RoleRepository) Revoke(userID int, role string) error {
+	query := `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`
+	return execAffectingRow(r.db, query, userID, role)
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"time"
+
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(tx *sqlx.Tx, session *models.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, expires_at, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, last_seen_at`
+	return tx.QueryRow(query, session.ID, session.UserID, session.ExpiresAt, session.UserAgent, session.IP).
+		Scan(&session.CreatedAt, &session.LastSeenAt)
+}
+
+// Touch отмечает активность сессии при обмене refresh токена
+func (r *SessionRepository) Touch(tx *sqlx.Tx, id string, client models.ClientInfo, expiresAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET last_seen_at = CURRENT_TIMESTAMP, expires_at = $2,
+			user_agent = COALESCE(NULLIF($3, ''), user_agent), ip = COALESCE(NULLIF($4, ''), ip)
+		WHERE id = $1 AND revoked_at IS NULL`
+	_, err := tx.Exec(query, id, expiresAt, client.UserAgent, client.IP)
+	return err
+}
+
+// GetActiveByUser возвращает неотозванные и неистёкшие сессии, последние активные первыми
+func (r *SessionRepository) GetActiveByUser(userID int) ([]models.Session, error) {
+	sessions := []models.Session{}
+	query := `
+		SELECT * FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_seen_at DESC`
+	err := r.db.Select(&sessions, query, userID)
+	return sessions, err
+}
+
+// Revoke отзывает сессию пользователя
+func (r *SessionRepository) Revoke(userID int, id string) error {
+	query := `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	return execAffectingRow(r.db, query, id, userID)
+}
+
+// RevokeAllExcept отзывает все сессии пользователя, кроме keepID, и возвращает отозванные
+func (r *SessionRepository) RevokeAllExcept(userID int, keepID string) ([]string, error) {
+	ids := []string{}
+	query := `
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		RETURNING id`
+	err := r.db.Select(&ids, query, userID, keepID)
+	return ids, err
+}
+
+// RevokeByRefreshTokenHash отзывает сессию, к которой относится refresh токен, и возвращает её id
+func (r *SessionRepository) RevokeByRefreshTokenHash(tokenHash string) ([]string, error) {
+	ids := []string{}
+	query := `
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1) AND revoked_at IS NULL
+		RETURNING id`
+	err := r.db.Select(&ids, query, tokenHash)
+	return ids, err
+}
+
+// GetRevokedSince возвращает сессии, отозванные после since: id -> время отзыва
+func (r *SessionRepository) GetRevokedSince(since time.Time) (map[string]time.Time, error) {
+	var rows []struct {
+		ID        string    `db:"id"`
+		RevokedAt time.Time `db:"revoked_at"`
+	}
+	query := `SELECT id, revoked_at FROM sessions WHERE revoked_at > $1`
+	if err := r.db.Select(&rows, query, since); err != nil {
+		return nil, err
+	}
+
+	revoked := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		revoked[row.ID] = row.RevokedAt
+	}
+	return revoked, nil
+}
+
+// DeleteStale удаляет истёкшие сессии и сессии, отозванные раньше revokedBefore
+func (r *SessionRepository) DeleteStale(revokedBefore time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM sessions WHERE expires_at < CURRENT_TIMESTAMP OR revoked_at < $1`, revokedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
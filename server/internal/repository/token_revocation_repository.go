@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TokenRevocationRepository хранит отозванные access токены и момент,
+// раньше которого все токены пользователя недействительны
+type TokenRevocationRepository struct {
+	db *sqlx.DB
+}
+
+func NewTokenRevocationRepository(db *sqlx.DB) *TokenRevocationRepository {
+	return &TokenRevocationRepository{db: db}
+}
+
+func (r *TokenRevocationRepository) Revoke(tokenID string, userID int, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING`
+	_, err := r.db.Exec(query, tokenID, userID, expiresAt)
+	return err
+}
+
+// GetRevoked возвращает ещё не истёкшие отозванные токены: jti -> срок действия
+func (r *TokenRevocationRepository) GetRevoked() (map[string]time.Time, error) {
+	var rows []struct {
+		TokenID   string    `db:"jti"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+	query := `SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > CURRENT_TIMESTAMP`
+	if err := r.db.Select(&rows, query); err != nil {
+		return nil, err
+	}
+
+	revoked := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		revoked[row.TokenID] = row.ExpiresAt
+	}
+	return revoked, nil
+}
+
+// InvalidateUserTokens делает недействительными токены пользователя, выданные раньше validAfter
+func (r *TokenRevocationRepository) InvalidateUserTokens(userID int, validAfter time.Time) error {
+	query := `UPDATE users SET tokens_valid_after = $2 WHERE id = $1`
+	_, err := r.db.Exec(query, userID, validAfter)
+	return err
+}
+
+// GetTokensValidAfter возвращает пользователей, у которых токены отзывались позже since
+func (r *TokenRevocationRepository) GetTokensValidAfter(since time.Time) (map[int]time.Time, error) {
+	var rows []struct {
+		UserID     int       `db:"id"`
+		ValidAfter time.Time `db:"tokens_valid_after"`
+	}
+	query := `SELECT id, tokens_valid_after FROM users WHERE tokens_valid_after > $1`
+	if err := r.db.Select(&rows, query, since); err != nil {
+		return nil, err
+	}
+
+	validAfter := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		validAfter[row.UserID] = row.ValidAfter
+	}
+	return validAfter, nil
+}
+
+func (r *TokenRevocationRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
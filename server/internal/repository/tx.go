@@ -0,0 +1,27 @@
+package repository
+
+import "github.com/jmoiron/sqlx"
+
+// TxManager выполняет операции нескольких репозиториев в одной транзакции
+type TxManager struct {
+	db *sqlx.DB
+}
+
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithTx открывает транзакцию, выполняет fn и фиксирует её, если fn не вернула ошибку
+func (m *TxManager) WithTx(fn func(tx *sqlx.Tx) error) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type UserIdentityRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserIdentityRepository(db *sqlx.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+func (r *UserIdentityRepository) Get(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Get(&identity, `SELECT * FROM user_identities WHERE provider = $1 AND subject = $2`, provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *UserIdentityRepository) ListByUser(userID int) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	err := r.db.Select(&identities, `SELECT * FROM user_identities WHERE user_id = $1 ORDER BY provider`, userID)
+	return identities, err
+}
+
+// ListByUserTx читает привязки в транзакции, в которой заблокирована строка пользователя
+func (r *UserIdentityRepository) ListByUserTx(tx *sqlx.Tx, userID int) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	err := tx.Select(&identities, `SELECT * FROM user_identities WHERE user_id = $1 ORDER BY provider`, userID)
+	return identities, err
+}
+
+func (r *UserIdentityRepository) Create(tx *sqlx.Tx, identity *models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, email_verified, name, avatar_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, linked_at, updated_at`
+	return tx.QueryRow(query, identity.UserID, identity.Provider, identity.Subject, identity.Email,
+		identity.EmailVerified, identity.Name, identity.AvatarURL).
+		Scan(&identity.ID, &identity.LinkedAt, &identity.UpdatedAt)
+}
+
+// UpdateProfile обновляет снимок профиля у провайдера
+func (r *UserIdentityRepository) UpdateProfile(identity *models.UserIdentity) error {
+	query := `
+		UPDATE user_identities
+		SET email = $3, email_verified = $4, name = $5, avatar_url = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE provider = $1 AND subject = $2`
+	return execAffectingRow(r.db, query, identity.Provider, identity.Subject,
+		identity.Email, identity.EmailVerified, identity.Name, identity.AvatarURL)
+}
+
+func (r *UserIdentityRepository) Delete(tx *sqlx.Tx, userID int, provider string) error {
+	query := `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`
+	return execAffectingRow(tx, query, userID, provider)
+}
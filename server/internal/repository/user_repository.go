@@ -21,10 +21,16 @@ func (r *UserRepository) Create(user *models.User) error {
 		return err
 	}
 
+	// Новый пользователь сразу получает роль покупателя
 	query := `
-		INSERT INTO users (email, password, first_name, last_name, phone, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at`
+		WITH u AS (
+			INSERT INTO users (email, password, first_name, last_name, phone, is_active)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at, updated_at
+		), r AS (
+			INSERT INTO user_roles (user_id, role) SELECT id, 'customer' FROM u
+		)
+		SELECT id, created_at, updated_at FROM u`
 
 	err = r.db.QueryRow(query,
 		user.Email,
@@ -40,7 +46,7 @@ func (r *UserRepository) Create(user *models.User) error {
 
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, created_at, updated_at, email, password, first_name, last_name, phone, is_active 
+	query := `SELECT id, created_at, updated_at, email, password, first_name, last_name, phone, is_active, email_verified_at
 			  FROM users WHERE email = $1 AND is_active = true`
 
 	err := r.db.Get(&user, query, email)
@@ -51,9 +57,24 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+// GetByEmailIncludingInactive ищет пользователя по email, в том числе удалённого:
+// email удалённого аккаунта остаётся занятым
+func (r *UserRepository) GetByEmailIncludingInactive(email string) (*models.User, error) {
+	var user models.User
+	query := `SELECT id, created_at, updated_at, email, password, first_name, last_name, phone, is_active, email_verified_at
+			  FROM users WHERE email = $1`
+
+	err := r.db.Get(&user, query, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, created_at, updated_at, email, password, first_name, last_name, phone, is_active 
+	query := `SELECT id, created_at, updated_at, email, password, first_name, last_name, phone, is_active, email_verified_at
 			  FROM users WHERE id = $1 AND is_active = true`
 
 	err := r.db.Get(&user, query, id)
@@ -64,6 +85,14 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
+// GetRoles возвращает названия ролей пользователя
+func (r *UserRepository) GetRoles(userID int) ([]string, error) {
+	roles := []string{}
+	query := `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`
+	err := r.db.Select(&roles, query, userID)
+	return roles, err
+}
+
 func (r *UserRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users 
@@ -76,16 +105,35 @@ func (r *UserRepository) Update(user *models.User) error {
 }
 
 func (r *UserRepository) UpdatePassword(userID int, newPassword string) error {
+	return updatePassword(r.db, userID, newPassword)
+}
+
+// UpdatePasswordTx меняет пароль в транзакции вызывающего кода
+func (r *UserRepository) UpdatePasswordTx(tx *sqlx.Tx, userID int, newPassword string) error {
+	return updatePassword(tx, userID, newPassword)
+}
+
+func updatePassword(db sqlx.Execer, userID int, newPassword string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
 	query := `UPDATE users SET password = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
-	_, err = r.db.Exec(query, userID, string(hashedPassword))
+	_, err = db.Exec(query, userID, string(hashedPassword))
 	return err
 }
 
+// MarkEmailVerified подтверждает email пользователя, если он не изменился с момента
+// отправки ссылки. Повторное подтверждение не меняет дату.
+func (r *UserRepository) MarkEmailVerified(userID int, email string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND email = $2 AND is_active = true`
+	return execAffectingRow(r.db, query, userID, email)
+}
+
 func (r *UserRepository) Delete(id int) error {
 	query := `UPDATE users SET is_active = false, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
 	_, err := r.db.Exec(query, id)
@@ -104,44 +152,49 @@ func (r *UserRepository) ValidatePassword(password, hashedPassword string) error
 }
 
 // OAuth2 methods
-func (r *UserRepository) GetByGoogleID(googleID string) (*models.User, error) {
-	var user models.User
-	query := `SELECT id, created_at, updated_at, email, first_name, last_name, phone, is_active, google_id, github_id, avatar_url, auth_provider FROM users WHERE google_id = $1`
-	err := r.db.Get(&user, query, googleID)
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
-}
 
-func (r *UserRepository) GetByGithubID(githubID string) (*models.User, error) {
+// GetByOAuthID ищет пользователя по аккаунту провайдера
+func (r *UserRepository) GetByOAuthID(provider, subject string) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, created_at, updated_at, email, first_name, last_name, phone, is_active, google_id, github_id, avatar_url, auth_provider FROM users WHERE github_id = $1`
-	err := r.db.Get(&user, query, githubID)
+	query := `
+		SELECT u.id, u.created_at, u.updated_at, u.email, u.first_name, u.last_name, COALESCE(u.phone, '') AS phone,
+			u.is_active, u.email_verified_at, COALESCE(u.avatar_url, '') AS avatar_url, u.auth_provider
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.provider = $1 AND i.subject = $2`
+	err := r.db.Get(&user, query, provider, subject)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) CreateOAuth(user *models.User) (*models.User, error) {
+// CreateOAuth создаёт пользователя, вошедшего через провайдера. Аккаунт
+// провайдера привязывается в той же транзакции.
+func (r *UserRepository) CreateOAuth(tx *sqlx.Tx, user *models.User) (*models.User, error) {
+	// У OAuth пользователей нет пароля: пустой хэш не пройдёт проверку bcrypt
 	query := `
-		INSERT INTO users (email, first_name, last_name, phone, is_active, google_id, github_id, avatar_url, auth_provider, created_at, updated_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		RETURNING id, created_at, updated_at, email, first_name, last_name, phone, is_active, google_id, github_id, avatar_url, auth_provider`
+		WITH u AS (
+			INSERT INTO users (email, password, first_name, last_name, phone, is_active, avatar_url, auth_provider, email_verified_at, created_at, updated_at)
+			VALUES ($1, '', $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			RETURNING id, created_at, updated_at, email, first_name, last_name, COALESCE(phone, '') AS phone, is_active, email_verified_at,
+				COALESCE(avatar_url, '') AS avatar_url, auth_provider
+		), r AS (
+			INSERT INTO user_roles (user_id, role) SELECT id, 'customer' FROM u
+		)
+		SELECT * FROM u`
 
 	var createdUser models.User
-	err := r.db.QueryRow(
+	err := tx.QueryRow(
 		query,
 		user.Email,
 		user.FirstName,
 		user.LastName,
 		user.Phone,
 		user.IsActive,
-		user.GoogleID,
-		user.GithubID,
 		user.AvatarURL,
 		user.AuthProvider,
+		user.EmailVerifiedAt,
 	).Scan(
 		&createdUser.ID,
 		&createdUser.CreatedAt,
@@ -151,8 +204,7 @@ func (r *UserRepository) CreateOAuth(user *models.User) (*models.User, error) {
 		&createdUser.LastName,
 		&createdUser.Phone,
 		&createdUser.IsActive,
-		&createdUser.GoogleID,
-		&createdUser.GithubID,
+		&createdUser.EmailVerifiedAt,
 		&createdUser.AvatarURL,
 		&createdUser.AuthProvider,
 	)
@@ -164,13 +216,31 @@ func (r *UserRepository) CreateOAuth(user *models.User) (*models.User, error) {
 	return &createdUser, nil
 }
 
-func (r *UserRepository) LinkOAuth(userID int, provider string, googleID, githubID, avatarURL string) error {
+// SetAvatarIfEmpty берёт аватар провайдера, только если своего у пользователя нет
+func (r *UserRepository) SetAvatarIfEmpty(tx *sqlx.Tx, userID int, avatarURL string) error {
 	query := `
-		UPDATE users 
-		SET google_id = $2, github_id = $3, avatar_url = $4, auth_provider = $1, updated_at = CURRENT_TIMESTAMP 
-		WHERE id = $1
-	`
+		UPDATE users
+		SET avatar_url = COALESCE(NULLIF(avatar_url, ''), NULLIF($2, '')), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`
+	return execAffectingRow(tx, query, userID, avatarURL)
+}
 
-	_, err := r.db.Exec(query, provider, userID, googleID, githubID, avatarURL)
-	return err
+// GetByIDForUpdate блокирует строку пользователя до конца транзакции
+func (r *UserRepository) GetByIDForUpdate(tx *sqlx.Tx, id int) (*models.User, error) {
+	var user models.User
+	query := `SELECT id, created_at, updated_at, email, password, first_name, last_name, COALESCE(phone, '') AS phone, is_active, email_verified_at,
+		COALESCE(avatar_url, '') AS avatar_url, auth_provider
+		FROM users WHERE id = $1 AND is_active = true FOR UPDATE`
+
+	err := tx.Get(&user, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetAuthProvider записывает основной способ входа
+func (r *UserRepository) SetAuthProvider(tx *sqlx.Tx, userID int, authProvider string) error {
+	query := `UPDATE users SET auth_provider = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	return execAffectingRow(tx, query, userID, authProvider)
 }
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"tenderness/internal/domain/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type WishlistRepository struct {
+	db *sqlx.DB
+}
+
+func NewWishlistRepository(db *sqlx.DB) *WishlistRepository {
+	return &WishlistRepository{db: db}
+}
+
+// Add добавляет товар в избранное; повторное добавление того же товара ничего не меняет
+func (r *WishlistRepository) Add(userID, productID int, variantID *int) error {
+	query := `
+		INSERT INTO wishlist_items (user_id, product_id, variant_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, product_id, (COALESCE(variant_id, 0))) DO NOTHING`
+	_, err := r.db.Exec(query, userID, productID, variantID)
+	return err
+}
+
+func (r *WishlistRepository) GetItem(userID, itemID int) (*models.WishlistItem, error) {
+	var item models.WishlistItem
+	query := `SELECT * FROM wishlist_items WHERE user_id = $1 AND id = $2`
+	err := r.db.Get(&item, query, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *WishlistRepository) GetLines(userID int) ([]models.WishlistLine, error) {
+	lines := []models.WishlistLine{}
+	query := `
+		SELECT w.id, w.created_at, w.product_id, p.name AS product_name, w.variant_id,
+			   COALESCE(v.sku, '') AS sku,
+			   COALESCE(concat_ws(' / ', NULLIF(v.size, ''), NULLIF(v.color, ''), NULLIF(v.material, '')), '') AS variant_name,
+			   COALESCE(v.image_urls[1], p.image_url, '') AS image_url,
+			   COALESCE(v.price, p.price) AS price, COALESCE(v.in_stock, p.in_stock) AND p.deleted_at IS NULL AS in_stock
+		FROM wishlist_items w
+		JOIN products p ON p.id = w.product_id
+		LEFT JOIN product_variants v ON v.id = w.variant_id
+		WHERE w.user_id = $1
+		ORDER BY w.created_at DESC, w.id DESC`
+	err := r.db.Select(&lines, query, userID)
+	return lines, err
+}
+
+func (r *WishlistRepository) Remove(userID, itemID int) error {
+	query := `DELETE FROM wishlist_items WHERE user_id = $1 AND id = $2`
+	return execAffectingRow(r.db, query, userID, itemID)
+}
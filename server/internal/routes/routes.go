@@ -1,46 +1,146 @@
 package routes
 
 import (
+	"tenderness/internal/domain/models"
 	"tenderness/internal/handlers"
 	"tenderness/internal/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App, healthHandler *handlers.HealthHandler, productHandler *handlers.ProductHandler, authHandler *handlers.AuthHandler, oauth2Handler *handlers.OAuth2Handler, jwt *middleware.JWTMiddleware) {
+func SetupRoutes(app *fiber.App, healthHandler *handlers.HealthHandler, jwksHandler *handlers.JWKSHandler, productHandler *handlers.ProductHandler, authHandler *handlers.AuthHandler, passwordResetHandler *handlers.PasswordResetHandler, emailVerificationHandler *handlers.EmailVerificationHandler, mfaHandler *handlers.MFAHandler, oauth2Handler *handlers.OAuth2Handler, cartHandler *handlers.CartHandler, orderHandler *handlers.OrderHandler, paymentHandler *handlers.PaymentHandler, reviewHandler *handlers.ReviewHandler, wishlistHandler *handlers.WishlistHandler, accountHandler *handlers.AccountHandler, sessionHandler *handlers.SessionHandler, adminHandler *handlers.AdminHandler, jwt *middleware.JWTMiddleware, roles *middleware.RoleMiddleware, limiter *middleware.RateLimiter) {
 	app.Get("/health", healthHandler.Check)
+	app.Get("/.well-known/jwks.json", jwksHandler.Get)
 
 	api := app.Group("/api")
 
-	// Auth routes (public)
-	auth := api.Group("/auth")
+	// Auth routes (public, strict rate limit against credential stuffing)
+	auth := api.Group("/auth", limiter.LimitByIP(middleware.RateLimitAuth))
 	auth.Post("/register", authHandler.Register)
 	auth.Post("/login", authHandler.Login)
-	auth.Post("/logout", authHandler.Logout)
+	auth.Post("/login/mfa", mfaHandler.Login)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/logout", jwt.OptionalJWTAuth(), authHandler.Logout)
+	auth.Post("/password/forgot", passwordResetHandler.Forgot)
+	auth.Post("/password/reset", passwordResetHandler.Reset)
+	auth.Post("/verify-email", emailVerificationHandler.Verify)
+	auth.Post("/verify-email/resend", emailVerificationHandler.Resend)
 
 	// OAuth2 routes
-	oauth2 := api.Group("/oauth2")
+	oauth2 := api.Group("/oauth2", limiter.LimitByIP(middleware.RateLimitAuth))
+	oauth2.Get("/providers", oauth2Handler.GetProviders)
 	oauth2.Get("/:provider/auth", oauth2Handler.GetAuthURL)
 	oauth2.Get("/:provider/callback", oauth2Handler.Callback)
 
 	// Protected routes
 	protected := api.Group("/user")
-	protected.Use(jwt.JWTAuth())
+	protected.Use(jwt.JWTAuth(), limiter.Limit(middleware.RateLimitUser))
 	protected.Get("/profile", authHandler.GetProfile)
 	protected.Put("/profile", authHandler.UpdateProfile)
 	protected.Put("/password", authHandler.ChangePassword)
 	protected.Delete("/account", authHandler.DeleteAccount)
+	protected.Post("/logout-all", authHandler.LogoutEverywhere)
+	protected.Get("/export", accountHandler.Export)
+
+	// Sessions (protected)
+	protected.Get("/sessions", sessionHandler.List)
+	protected.Delete("/sessions", sessionHandler.RevokeOthers)
+	protected.Delete("/sessions/:id", sessionHandler.Revoke)
+
+	// Two-factor authentication (protected)
+	protected.Get("/mfa", mfaHandler.GetStatus)
+	protected.Post("/mfa/totp", mfaHandler.EnrollTOTP)
+	protected.Post("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+	protected.Post("/mfa/totp/disable", mfaHandler.DisableTOTP)
+	protected.Post("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
 
 	// OAuth2 linking (protected)
+	protected.Get("/identities", oauth2Handler.GetLinkedAccounts)
+	protected.Post("/identities/confirm", oauth2Handler.ConfirmLink)
 	protected.Post("/link/:provider", oauth2Handler.LinkAccount)
 	protected.Delete("/unlink/:provider", oauth2Handler.UnlinkAccount)
 
-	// Product routes
-	api.Get("/products", productHandler.GetProducts)
-	api.Get("/products/featured", productHandler.GetFeaturedProducts)
-	api.Get("/products/search", productHandler.SearchProducts)
-	api.Get("/products/category/:category", productHandler.GetProductsByCategory)
-	api.Get("/products/:id", productHandler.GetProductByID)
+	// Cart (protected)
+	protected.Get("/cart", cartHandler.GetCart)
+	protected.Delete("/cart", cartHandler.Clear)
+	protected.Post("/cart/items", cartHandler.AddItem)
+	protected.Put("/cart/items/:id", cartHandler.UpdateItem)
+	protected.Delete("/cart/items/:id", cartHandler.RemoveItem)
+
+	// Orders (protected)
+	protected.Post("/orders", emailVerificationHandler.RequireVerifiedForCheckout, orderHandler.PlaceOrder)
+	protected.Get("/orders", orderHandler.GetOrders)
+	protected.Get("/orders/:id", orderHandler.GetOrder)
+	protected.Post("/orders/:id/cancel", orderHandler.CancelOrder)
+	protected.Post("/orders/:id/payment", emailVerificationHandler.RequireVerifiedForCheckout, paymentHandler.StartPayment)
+	protected.Post("/orders/:id/payment/confirm", paymentHandler.ConfirmPayment)
+
+	// Reviews (protected)
+	protected.Post("/reviews", reviewHandler.CreateReview)
+	protected.Put("/reviews/:id", reviewHandler.UpdateReview)
+	protected.Delete("/reviews/:id", reviewHandler.DeleteReview)
+
+	// Wishlist (protected)
+	protected.Get("/wishlist", wishlistHandler.GetWishlist)
+	protected.Post("/wishlist", wishlistHandler.AddItem)
+	protected.Delete("/wishlist/:id", wishlistHandler.RemoveItem)
+	protected.Post("/wishlist/:id/move-to-cart", wishlistHandler.MoveToCart)
+
+	// Staff routes, each group requires its own permission
+	admin := api.Group("/admin")
+	admin.Use(jwt.JWTAuth(), limiter.Limit(middleware.RateLimitUser))
+
+	products := admin.Group("/products", roles.RequirePermission(models.PermissionCatalogManage))
+	products.Get("/", adminHandler.GetProducts)
+	products.Post("/", adminHandler.CreateProduct)
+	products.Put("/:id", adminHandler.UpdateProduct)
+	products.Delete("/:id", adminHandler.DeleteProduct)
+	products.Post("/:id/restore", adminHandler.RestoreProduct)
+	products.Get("/:id/variants", adminHandler.GetVariants)
+	products.Post("/:id/variants", adminHandler.CreateVariant)
+	products.Put("/:id/variants/:variantId", adminHandler.UpdateVariant)
+	products.Delete("/:id/variants/:variantId", adminHandler.DeleteVariant)
+
+	categories := admin.Group("/categories", roles.RequirePermission(models.PermissionCatalogManage))
+	categories.Get("/", adminHandler.GetCategories)
+	categories.Post("/", adminHandler.CreateCategory)
+	categories.Put("/:id", adminHandler.UpdateCategory)
+	categories.Delete("/:id", adminHandler.DeleteCategory)
+	categories.Post("/:id/restore", adminHandler.RestoreCategory)
+
+	orders := admin.Group("/orders", roles.RequirePermission(models.PermissionOrdersManage))
+	orders.Get("/:id", adminHandler.GetOrder)
+	orders.Put("/:id/status", adminHandler.UpdateOrderStatus)
+	orders.Post("/:id/refund", adminHandler.RefundOrder)
+
+	// Группы по полному префиксу: middleware группы /users действовал бы и на соседние маршруты
+	userRoles := admin.Group("/users/:id/roles", roles.RequirePermission(models.PermissionRolesManage))
+	userRoles.Get("/", adminHandler.GetUserRoles)
+	userRoles.Post("/", adminHandler.GrantRole)
+	userRoles.Delete("/:role", adminHandler.RevokeRole)
+
+	admin.Delete("/users/:id/lockout", roles.RequirePermission(models.PermissionUsersUnlock), adminHandler.UnlockLogin)
+
+	// Payment provider webhooks (authenticated by provider signature)
+	api.Post("/payments/webhook/:provider", paymentHandler.Webhook)
+
+	// Guest cart (works for anonymous visitors and signed in users)
+	guestCart := api.Group("/cart")
+	guestCart.Use(jwt.OptionalJWTAuth(), limiter.Limit(middleware.RateLimitUser))
+	guestCart.Get("/", cartHandler.GetCart)
+	guestCart.Delete("/", cartHandler.Clear)
+	guestCart.Post("/items", cartHandler.AddItem)
+	guestCart.Put("/items/:id", cartHandler.UpdateItem)
+	guestCart.Delete("/items/:id", cartHandler.RemoveItem)
+
+	// Product routes (public, lenient rate limit)
+	catalog := limiter.LimitByIP(middleware.RateLimitCatalog)
+	api.Get("/products", catalog, productHandler.GetProducts)
+	api.Get("/products/featured", catalog, productHandler.GetFeaturedProducts)
+	api.Get("/products/search", catalog, productHandler.SearchProducts)
+	api.Get("/products/category/:category", catalog, productHandler.GetProductsByCategory)
+	api.Get("/products/:id", catalog, productHandler.GetProductByID)
+	api.Get("/products/:id/reviews", catalog, reviewHandler.GetProductReviews)
 
-	api.Get("/categories", productHandler.GetCategories)
+	api.Get("/categories", catalog, productHandler.GetCategories)
 }
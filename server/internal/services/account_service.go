@@ -0,0 +1,64 @@
+package services
+
+import (
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+)
+
+type AccountService struct {
+	authService  *AuthService
+	orderRepo    *repository.OrderRepository
+	reviewRepo   *repository.ReviewRepository
+	wishlistRepo *repository.WishlistRepository
+}
+
+func NewAccountService(authService *AuthService, orderRepo *repository.OrderRepository, reviewRepo *repository.ReviewRepository, wishlistRepo *repository.WishlistRepository) *AccountService {
+	return &AccountService{
+		authService:  authService,
+		orderRepo:    orderRepo,
+		reviewRepo:   reviewRepo,
+		wishlistRepo: wishlistRepo,
+	}
+}
+
+// Export собирает данные аккаунта для выгрузки: профиль, заказы с позициями
+// и историей статусов, отзывы и избранное
+func (s *AccountService) Export(userID int) (*models.AccountExport, error) {
+	profile, err := s.authService.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.AccountExport{
+		ExportedAt: time.Now(),
+		Profile:    *profile,
+	}
+
+	total, err := s.orderRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Orders, err = s.orderRepo.GetByUserID(userID, int(total), 0); err != nil {
+		return nil, err
+	}
+	for i := range export.Orders {
+		order := &export.Orders[i]
+		if order.Items, err = s.orderRepo.GetItems(order.ID); err != nil {
+			return nil, err
+		}
+		if order.History, err = s.orderRepo.GetHistory(order.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if export.Reviews, err = s.reviewRepo.GetByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.Wishlist, err = s.wishlistRepo.GetLines(userID); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
@@ -2,7 +2,6 @@ package services
 
 import (
 	"errors"
-	"reflect"
 	"tenderness/internal/domain/models"
 	"tenderness/internal/middleware"
 	"tenderness/internal/repository"
@@ -11,31 +10,26 @@ import (
 )
 
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwt       *middleware.JWTMiddleware
-	validator *validator.Validate
+	userRepo     *repository.UserRepository
+	tokens       *TokenService
+	verification *EmailVerificationService
+	mfa          *MFAService
+	throttle     *LoginThrottleService
+	validator    *validator.Validate
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwt *middleware.JWTMiddleware) *AuthService {
-	v := validator.New()
-
-	// Регистрируем кастомный валидатор для использования JSON имен вместо имен полей структуры
-	v.RegisterTagNameFunc(func(field reflect.StructField) string {
-		name := field.Tag.Get("json")
-		if name == "" {
-			return field.Name
-		}
-		return name
-	})
-
+func NewAuthService(userRepo *repository.UserRepository, tokens *TokenService, verification *EmailVerificationService, mfa *MFAService, throttle *LoginThrottleService) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwt:       jwt,
-		validator: v,
+		userRepo:     userRepo,
+		tokens:       tokens,
+		verification: verification,
+		mfa:          mfa,
+		throttle:     throttle,
+		validator:    newValidator(),
 	}
 }
 
-func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+func (s *AuthService) Register(req *models.RegisterRequest, client models.ClientInfo) (*models.AuthResponse, error) {
 	if err := s.validator.Struct(req); err != nil {
 		return nil, err
 	}
@@ -61,64 +55,99 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.AuthRespons
 		return nil, err
 	}
 
-	token, err := s.jwt.GenerateToken(user.ID, user.Email)
+	s.verification.SendVerification(user)
+
+	roles, err := s.userRepo.GetRoles(user.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	userResponse := &models.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Phone:     user.Phone,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
-	}
-
-	return &models.AuthResponse{
-		User:  *userResponse,
-		Token: token,
-	}, nil
+		ID:            user.ID,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Phone:         user.Phone,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Roles:         roles,
+		CreatedAt:     user.CreatedAt,
+	}
+
+	// Без подтверждённого email войти нельзя, поэтому токены выдаются только после подтверждения
+	if s.verification.RequiredForLogin() {
+		return &models.AuthResponse{
+			User:                      *userResponse,
+			EmailVerificationRequired: true,
+		}, nil
+	}
+
+	return s.tokens.NewAuthResponse(*userResponse, client)
 }
 
-func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+// Login проверяет email и пароль. Если у пользователя включён второй фактор,
+// вместо токенов возвращается MFAChallenge, который обменивается на токены
+// через MFAService.CompleteLogin. Неудачные попытки учитываются по email и по IP.
+func (s *AuthService) Login(req *models.LoginRequest, client models.ClientInfo) (*models.AuthResponse, *models.MFAChallenge, error) {
 	if err := s.validator.Struct(req); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	accountKey, ipKey := LoginAccountKey(req.Email), LoginIPKey(client.IP)
+	if err := s.throttle.Check(accountKey, ipKey); err != nil {
+		return nil, nil, err
 	}
 
 	user, err := s.userRepo.GetByEmail(req.Email)
+	if err == nil {
+		err = s.userRepo.ValidatePassword(req.Password, user.Password)
+	}
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		if err := s.throttle.RegisterFailure(accountKey, ipKey); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("invalid email or password")
 	}
 
-	if err := s.userRepo.ValidatePassword(req.Password, user.Password); err != nil {
-		return nil, errors.New("invalid email or password")
+	if err := s.throttle.Succeeded(accountKey); err != nil {
+		return nil, nil, err
 	}
 
 	if !user.IsActive {
-		return nil, errors.New("user account is deactivated")
+		return nil, nil, errors.New("user account is deactivated")
+	}
+
+	if err := s.verification.CheckLogin(user); err != nil {
+		return nil, nil, err
 	}
 
-	token, err := s.jwt.GenerateToken(user.ID, user.Email)
+	challenge, err := s.mfa.Challenge(user.ID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
+	}
+
+	roles, err := s.userRepo.GetRoles(user.ID)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	userResponse := &models.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Phone:     user.Phone,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
-	}
-
-	return &models.AuthResponse{
-		User:  *userResponse,
-		Token: token,
-	}, nil
+		ID:            user.ID,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Phone:         user.Phone,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Roles:         roles,
+		CreatedAt:     user.CreatedAt,
+	}
+
+	response, err := s.tokens.NewAuthResponse(*userResponse, client)
+	return response, nil, err
 }
 
 func (s *AuthService) GetProfile(userID int) (*models.UserResponse, error) {
@@ -127,14 +156,21 @@ func (s *AuthService) GetProfile(userID int) (*models.UserResponse, error) {
 		return nil, err
 	}
 
+	roles, err := s.userRepo.GetRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
 	userResponse := &models.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Phone:     user.Phone,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
+		ID:            user.ID,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Phone:         user.Phone,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Roles:         roles,
+		CreatedAt:     user.CreatedAt,
 	}
 
 	return userResponse, nil
@@ -155,19 +191,48 @@ func (s *AuthService) UpdateProfile(userID int, req *models.User) error {
 	return s.userRepo.Update(user)
 }
 
-func (s *AuthService) ChangePassword(userID int, currentPassword, newPassword string) error {
+// ChangePassword меняет пароль и завершает все входы пользователя.
+// Текущему клиенту выдаётся новая пара токенов.
+func (s *AuthService) ChangePassword(userID int, currentPassword, newPassword string, client models.ClientInfo) (*models.AuthResponse, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := s.userRepo.ValidatePassword(currentPassword, user.Password); err != nil {
-		return errors.New("current password is incorrect")
+		return nil, errors.New("current password is incorrect")
+	}
+
+	if err := s.userRepo.UpdatePassword(userID, newPassword); err != nil {
+		return nil, err
 	}
 
-	return s.userRepo.UpdatePassword(userID, newPassword)
+	if err := s.tokens.RevokeAll(userID); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokens.NewAuthResponse(*profile, client)
 }
 
 func (s *AuthService) DeleteAccount(userID int) error {
-	return s.userRepo.Delete(userID)
+	if err := s.userRepo.Delete(userID); err != nil {
+		return err
+	}
+
+	return s.tokens.RevokeAll(userID)
+}
+
+// Logout завершает текущий вход
+func (s *AuthService) Logout(claims *middleware.JWTClaims, refreshToken string) error {
+	return s.tokens.Logout(claims, refreshToken)
+}
+
+// LogoutEverywhere завершает все входы пользователя на всех устройствах
+func (s *AuthService) LogoutEverywhere(userID int) error {
+	return s.tokens.RevokeAll(userID)
 }
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ErrProductNotFound   = errors.New("product not found")
+	ErrProductOutOfStock = errors.New("product is out of stock")
+	ErrCartItemNotFound  = errors.New("cart item not found")
+	ErrVariantNotFound   = errors.New("product variant not found")
+	ErrVariantRequired   = errors.New("product variant is required")
+	ErrInvalidGuestToken = errors.New("invalid guest cart token")
+)
+
+// Причины, по которым позиция гостевой корзины не была перенесена
+const (
+	mergeSkipUnavailable = "unavailable"
+	mergeSkipOutOfStock  = "out_of_stock"
+)
+
+type CartService struct {
+	cartRepo    *repository.CartRepository
+	productRepo *repository.ProductRepository
+	validator   *validator.Validate
+	guestSecret []byte
+}
+
+func NewCartService(cartRepo *repository.CartRepository, productRepo *repository.ProductRepository, guestSecret string) *CartService {
+	return &CartService{
+		cartRepo:    cartRepo,
+		productRepo: productRepo,
+		validator:   newValidator(),
+		guestSecret: []byte(guestSecret),
+	}
+}
+
+// NewGuestToken создаёт подписанный токен анонимной корзины в формате "<id>.<signature>"
+func (s *CartService) NewGuestToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	guestID := hex.EncodeToString(b)
+	return guestID + "." + s.signGuestID(guestID), nil
+}
+
+// ParseGuestToken проверяет подпись токена и возвращает идентификатор гостевой корзины
+func (s *CartService) ParseGuestToken(token string) (string, error) {
+	guestID, signature, ok := strings.Cut(token, ".")
+	if !ok || guestID == "" {
+		return "", ErrInvalidGuestToken
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.signGuestID(guestID))) {
+		return "", ErrInvalidGuestToken
+	}
+
+	return guestID, nil
+}
+
+func (s *CartService) signGuestID(guestID string) string {
+	mac := hmac.New(sha256.New, s.guestSecret)
+	mac.Write([]byte(guestID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *CartService) GetCart(owner models.CartOwner) (*models.CartResponse, error) {
+	cart, err := s.findCart(owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return &models.CartResponse{Items: []models.CartLine{}}, nil
+	}
+
+	return s.buildResponse(cart)
+}
+
+func (s *CartService) AddItem(owner models.CartOwner, req *models.AddCartItemRequest) (*models.CartResponse, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	product, price, err := s.getPurchasableItem(req.ProductID, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.getOrCreateCart(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cartRepo.AddItem(cart.ID, product.ID, req.VariantID, req.Quantity, price); err != nil {
+		return nil, err
+	}
+
+	return s.buildResponse(cart)
+}
+
+func (s *CartService) UpdateItem(owner models.CartOwner, itemID int, req *models.UpdateCartItemRequest) (*models.CartResponse, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.findCart(owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, ErrCartItemNotFound
+	}
+
+	item, err := s.cartRepo.GetItem(cart.ID, itemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCartItemNotFound
+		}
+		return nil, err
+	}
+
+	_, price, err := s.getPurchasableItem(item.ProductID, item.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cartRepo.UpdateItem(cart.ID, item.ID, req.Quantity, price); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCartItemNotFound
+		}
+		return nil, err
+	}
+
+	return s.buildResponse(cart)
+}
+
+func (s *CartService) RemoveItem(owner models.CartOwner, itemID int) (*models.CartResponse, error) {
+	cart, err := s.findCart(owner)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, ErrCartItemNotFound
+	}
+
+	if err := s.cartRepo.RemoveItem(cart.ID, itemID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCartItemNotFound
+		}
+		return nil, err
+	}
+
+	return s.buildResponse(cart)
+}
+
+func (s *CartService) Clear(owner models.CartOwner) error {
+	cart, err := s.findCart(owner)
+	if err != nil || cart == nil {
+		return err
+	}
+
+	return s.cartRepo.Clear(cart.ID)
+}
+
+// MergeGuestCart переносит гостевую корзину в корзину пользователя после входа.
+// Правила слияния:
+//   - товары, которые удалены или закончились, не переносятся и попадают в Skipped;
+//   - если товар уже есть в корзине пользователя, остаётся большее количество;
+//   - цена позиции обновляется до текущей цены товара.
+func (s *CartService) MergeGuestCart(guestID string, userID int) (*models.CartMergeResult, error) {
+	guestCart, err := s.cartRepo.GetByGuestToken(guestID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.CartMergeResult{}, nil
+		}
+		return nil, err
+	}
+
+	items, err := s.cartRepo.GetItems(guestCart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CartMergeResult{}
+	merged := make([]models.CartItem, 0, len(items))
+	for _, item := range items {
+		product, price, err := s.getPurchasableItem(item.ProductID, item.VariantID)
+		switch {
+		case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrVariantNotFound), errors.Is(err, ErrVariantRequired):
+			result.Skipped = append(result.Skipped, models.CartMergeSkip{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Reason:    mergeSkipUnavailable,
+			})
+			continue
+		case errors.Is(err, ErrProductOutOfStock):
+			result.Skipped = append(result.Skipped, models.CartMergeSkip{
+				ProductID:   item.ProductID,
+				VariantID:   item.VariantID,
+				ProductName: product.Name,
+				Reason:      mergeSkipOutOfStock,
+			})
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		item.UnitPrice = price
+		merged = append(merged, item)
+	}
+
+	userCart, err := s.cartRepo.GetOrCreateByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cartRepo.MergeGuestCart(guestCart.ID, userCart.ID, merged); err != nil {
+		return nil, err
+	}
+
+	result.Merged = len(merged)
+	return result, nil
+}
+
+// findCart возвращает корзину владельца; для гостя без корзины возвращает nil
+func (s *CartService) findCart(owner models.CartOwner) (*models.Cart, error) {
+	if !owner.IsGuest() {
+		return s.cartRepo.GetOrCreateByUserID(owner.UserID)
+	}
+	if owner.GuestID == "" {
+		return nil, nil
+	}
+
+	cart, err := s.cartRepo.GetByGuestToken(owner.GuestID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return cart, err
+}
+
+func (s *CartService) getOrCreateCart(owner models.CartOwner) (*models.Cart, error) {
+	if !owner.IsGuest() {
+		return s.cartRepo.GetOrCreateByUserID(owner.UserID)
+	}
+	if owner.GuestID == "" {
+		return nil, ErrInvalidGuestToken
+	}
+
+	return s.cartRepo.GetOrCreateByGuestToken(owner.GuestID)
+}
+
+// getPurchasableItem проверяет, что товар (и вариант, если у товара есть варианты)
+// существует и есть в наличии, и возвращает актуальную цену позиции
+func (s *CartService) getPurchasableItem(productID int, variantID *int) (*models.Product, float64, error) {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrProductNotFound
+		}
+		return nil, 0, err
+	}
+
+	if len(product.Variants) == 0 {
+		if variantID != nil {
+			return product, 0, ErrVariantNotFound
+		}
+		if !product.InStock {
+			return product, 0, ErrProductOutOfStock
+		}
+		return product, product.Price, nil
+	}
+
+	if variantID == nil {
+		return product, 0, ErrVariantRequired
+	}
+
+	for i := range product.Variants {
+		variant := &product.Variants[i]
+		if variant.ID != *variantID {
+			continue
+		}
+		if !variant.InStock {
+			return product, 0, ErrProductOutOfStock
+		}
+		return product, variant.EffectivePrice(product), nil
+	}
+
+	return product, 0, ErrVariantNotFound
+}
+
+func (s *CartService) buildResponse(cart *models.Cart) (*models.CartResponse, error) {
+	lines, err := s.cartRepo.GetLines(cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CartResponse{
+		ID:    cart.ID,
+		Items: lines,
+	}
+
+	for i := range response.Items {
+		line := &response.Items[i]
+		line.LineTotal = roundMoney(line.UnitPrice * float64(line.Quantity))
+		response.TotalItems += line.Quantity
+		response.Total += line.LineTotal
+	}
+	response.Total = roundMoney(response.Total)
+
+	return response, nil
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
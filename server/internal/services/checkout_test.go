@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/payments"
+	"tenderness/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+type checkoutFixture struct {
+	db       *sqlx.DB
+	user     *models.User
+	product  *models.Product
+	orders   *OrderService
+	payments *PaymentService
+	provider *payments.FakeProvider
+}
+
+func newCheckoutFixture(t *testing.T) *checkoutFixture {
+	t.Helper()
+
+	db := openTestDB(t)
+	txManager := repository.NewTxManager(db)
+	productRepo := repository.NewProductRepository(db)
+	orderService := NewOrderService(txManager, repository.NewOrderRepository(db), repository.NewCartRepository(db),
+		productRepo, repository.NewInventoryRepository(db), 30*time.Minute)
+	provider := payments.NewFakeProvider(testWebhookSecret, time.Now().Format("150405.000000"))
+
+	product := &models.Product{
+		Name:          "Test product",
+		Price:         1500.50,
+		Category:      "Test",
+		StockQuantity: 5,
+	}
+	if err := productRepo.Create(product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	return &checkoutFixture{
+		db:       db,
+		user:     createTestUser(t, db),
+		product:  product,
+		orders:   orderService,
+		payments: NewPaymentService(txManager, repository.NewPaymentRepository(db), orderService, payments.FakeProviderName, "RUB", provider),
+		provider: provider,
+	}
+}
+
+// placeOrder кладёт в корзину две единицы товара и оформляет заказ, резервируя их
+func (f *checkoutFixture) placeOrder(t *testing.T) *models.Order {
+	t.Helper()
+
+	cartRepo := repository.NewCartRepository(f.db)
+	cart, err := cartRepo.GetOrCreateByUserID(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cartRepo.AddItem(cart.ID, f.product.ID, nil, 2, f.product.Price); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := f.orders.PlaceOrder(f.user.ID, &models.PlaceOrderRequest{ShippingAddress: "Москва, ул. Тестовая, д. 1"})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+	return order
+}
+
+// pay проводит оплату на стороне провайдера и доставляет вебхук о ней
+func (f *checkoutFixture) pay(t *testing.T, intentID string) {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := f.provider.Confirm(ctx, intentID, payments.FakeCardSuccess); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.provider.Capture(ctx, intentID); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, signature, err := f.provider.NewWebhook(payments.EventPaymentSucceeded, intentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.deliver(t, payload, signature)
+}
+
+func (f *checkoutFixture) deliver(t *testing.T, payload []byte, signature string) (duplicate bool) {
+	t.Helper()
+
+	duplicate, err := f.payments.HandleWebhook(payments.FakeProviderName, payload, func(key string) string {
+		if key == f.provider.SignatureHeader() {
+			return signature
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("HandleWebhook: %v", err)
+	}
+	return duplicate
+}
+
+func (f *checkoutFixture) stock(t *testing.T) (stock, reserved int) {
+	t.Helper()
+
+	query := `SELECT stock_quantity, reserved_quantity FROM products WHERE id = $1`
+	if err := f.db.QueryRow(query, f.product.ID).Scan(&stock, &reserved); err != nil {
+		t.Fatal(err)
+	}
+	return stock, reserved
+}
+
+func (f *checkoutFixture) orderStatus(t *testing.T, orderID int) models.OrderStatus {
+	t.Helper()
+
+	order, err := f.orders.GetOrder(f.user.ID, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order.Status
+}
+
+func TestCheckoutPaidByWebhook(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	order := f.placeOrder(t)
+	if stock, reserved := f.stock(t); stock != 5 || reserved != 2 {
+		t.Fatalf("after reserve: stock=%d reserved=%d, want 5 and 2", stock, reserved)
+	}
+
+	started, err := f.payments.StartPayment(f.user.ID, order.ID)
+	if err != nil {
+		t.Fatalf("StartPayment: %v", err)
+	}
+	if started.Payment.Amount != 300100 {
+		t.Fatalf("payment amount = %d, want 300100", started.Payment.Amount)
+	}
+
+	intentID := started.Payment.ProviderPaymentID
+	ctx := context.Background()
+	if _, err := f.provider.Confirm(ctx, intentID, payments.FakeCardSuccess); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.provider.Capture(ctx, intentID); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, signature, err := f.provider.NewWebhook(payments.EventPaymentSucceeded, intentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.payments.HandleWebhook(payments.FakeProviderName, payload, func(string) string { return "bad" }); err == nil {
+		t.Fatal("webhook with an invalid signature was accepted")
+	}
+	if duplicate := f.deliver(t, payload, signature); duplicate {
+		t.Fatal("first webhook delivery reported as duplicate")
+	}
+
+	if status := f.orderStatus(t, order.ID); status != models.OrderStatusPaid {
+		t.Fatalf("order status = %s, want paid", status)
+	}
+	payment, err := repository.NewPaymentRepository(f.db).GetLatestByOrderID(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != models.PaymentStatusSucceeded {
+		t.Fatalf("payment status = %s, want succeeded", payment.Status)
+	}
+	if stock, reserved := f.stock(t); stock != 3 || reserved != 0 {
+		t.Fatalf("after payment: stock=%d reserved=%d, want 3 and 0", stock, reserved)
+	}
+
+	if duplicate := f.deliver(t, payload, signature); !duplicate {
+		t.Fatal("redelivered webhook was not reported as duplicate")
+	}
+}
+
+func TestCheckoutRefundsPaymentForCancelledOrder(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	order := f.placeOrder(t)
+	started, err := f.payments.StartPayment(f.user.ID, order.ID)
+	if err != nil {
+		t.Fatalf("StartPayment: %v", err)
+	}
+
+	// Заказ отменён, пока покупатель был на странице оплаты
+	if _, err := f.orders.CancelOrder(f.user.ID, order.ID); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	f.pay(t, started.Payment.ProviderPaymentID)
+
+	if status := f.orderStatus(t, order.ID); status != models.OrderStatusCancelled {
+		t.Fatalf("order status = %s, want cancelled", status)
+	}
+	payment, err := repository.NewPaymentRepository(f.db).GetLatestByOrderID(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != models.PaymentStatusRefunded {
+		t.Fatalf("payment status = %s, want refunded", payment.Status)
+	}
+	if stock, reserved := f.stock(t); stock != 5 || reserved != 0 {
+		t.Fatalf("after cancel: stock=%d reserved=%d, want 5 and 0", stock, reserved)
+	}
+}
+
+func TestRefundOrderIsIdempotent(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	order := f.placeOrder(t)
+	started, err := f.payments.StartPayment(f.user.ID, order.ID)
+	if err != nil {
+		t.Fatalf("StartPayment: %v", err)
+	}
+	f.pay(t, started.Payment.ProviderPaymentID)
+
+	payment, err := f.payments.RefundOrder(order.ID, nil)
+	if err != nil {
+		t.Fatalf("RefundOrder: %v", err)
+	}
+	if payment.Status != models.PaymentStatusRefunded {
+		t.Fatalf("payment status = %s, want refunded", payment.Status)
+	}
+	if status := f.orderStatus(t, order.ID); status != models.OrderStatusRefunded {
+		t.Fatalf("order status = %s, want refunded", status)
+	}
+	if stock, reserved := f.stock(t); stock != 5 || reserved != 0 {
+		t.Fatalf("after refund: stock=%d reserved=%d, want 5 and 0", stock, reserved)
+	}
+
+	if _, err := f.payments.RefundOrder(order.ID, nil); !errors.Is(err, ErrPaymentNotRefundable) {
+		t.Fatalf("second refund error = %v, want ErrPaymentNotRefundable", err)
+	}
+}
+
+func TestCheckoutRejectsWebhookWithAnotherAmount(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	order := f.placeOrder(t)
+	started, err := f.payments.StartPayment(f.user.ID, order.ID)
+	if err != nil {
+		t.Fatalf("StartPayment: %v", err)
+	}
+
+	// Корректно подписанный вебхук об оплате одной копейки
+	payload, _, err := f.provider.NewWebhook(payments.EventPaymentSucceeded, started.Payment.ProviderPaymentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(payload, &body); err != nil {
+		t.Fatal(err)
+	}
+	body["amount"] = 1
+	if payload, err = json.Marshal(body); err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(payload)
+	f.deliver(t, payload, hex.EncodeToString(mac.Sum(nil)))
+
+	if status := f.orderStatus(t, order.ID); status != models.OrderStatusPending {
+		t.Fatalf("order status = %s, want pending", status)
+	}
+	payment, err := repository.NewPaymentRepository(f.db).GetLatestByOrderID(order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != models.PaymentStatusFailed || payment.FailureReason != ErrPaymentAmountMismatch.Error() {
+		t.Fatalf("payment status = %s (%s), want failed with amount mismatch", payment.Status, payment.FailureReason)
+	}
+	if stock, reserved := f.stock(t); stock != 5 || reserved != 2 {
+		t.Fatalf("after rejected webhook: stock=%d reserved=%d, want 5 and 2", stock, reserved)
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/domain/storage"
+	"tenderness/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// openTestDB подключается к TEST_DATABASE_URL и применяет миграции. Сервисы работают
+// с PostgreSQL напрямую, поэтому без этой переменной тест пропускается.
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := storage.NewDatabase(dsn, storage.PoolOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrateOnce.Do(func() {
+		migrateErr = db.RunMigrations("../../migrations")
+	})
+	if migrateErr != nil {
+		t.Fatal(migrateErr)
+	}
+
+	return db.DB
+}
+
+// createTestUser создаёт покупателя с уникальным email, чтобы тесты можно было повторять на той же базе
+func createTestUser(t *testing.T, db *sqlx.DB) *models.User {
+	t.Helper()
+
+	user := &models.User{
+		Email:     fmt.Sprintf("test-%d@example.com", time.Now().UnixNano()),
+		Password:  "password123",
+		FirstName: "Test",
+		LastName:  "User",
+		IsActive:  true,
+	}
+	if err := repository.NewUserRepository(db).Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
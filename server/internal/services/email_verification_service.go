@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/mailer"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Режимы EMAIL_VERIFICATION_REQUIRED: что запрещено пользователю с неподтверждённым email
+const (
+	EmailVerificationOff      = "off"
+	EmailVerificationCheckout = "checkout"
+	EmailVerificationLogin    = "login"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+)
+
+type EmailVerificationService struct {
+	userRepo  *repository.UserRepository
+	mailer    mailer.Mailer
+	secret    []byte
+	baseURL   string
+	ttl       time.Duration
+	mode      string
+	validator *validator.Validate
+}
+
+func NewEmailVerificationService(userRepo *repository.UserRepository, m mailer.Mailer, secret, baseURL string, ttl time.Duration, mode string) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:  userRepo,
+		mailer:    m,
+		secret:    []byte(secret),
+		baseURL:   baseURL,
+		ttl:       ttl,
+		mode:      mode,
+		validator: newValidator(),
+	}
+}
+
+// RequiredForLogin сообщает, нужен ли подтверждённый email для входа
+func (s *EmailVerificationService) RequiredForLogin() bool {
+	return s.mode == EmailVerificationLogin
+}
+
+// CheckLogin запрещает вход с неподтверждённым email, если так настроено
+func (s *EmailVerificationService) CheckLogin(user *models.User) error {
+	if s.RequiredForLogin() && user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// CheckCheckout запрещает оформление заказа с неподтверждённым email, если так настроено
+func (s *EmailVerificationService) CheckCheckout(userID int) error {
+	if s.mode != EmailVerificationCheckout && s.mode != EmailVerificationLogin {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// SendVerification отправляет пользователю ссылку для подтверждения email.
+// Письмо уходит в фоне, ошибки отправки только логируются.
+func (s *EmailVerificationService) SendVerification(user *models.User) {
+	token := s.newToken(user.ID, user.Email, time.Now().Add(s.ttl))
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить адрес электронной почты, перейдите по ссылке:\n%s/verify-email?token=%s\n\n"+
+			"Ссылка действует %s. Если вы не регистрировались, просто проигнорируйте это письмо.\n",
+			user.FirstName, s.baseURL, url.QueryEscape(token), s.ttl),
+	}
+
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}()
+}
+
+// Resend повторно отправляет ссылку. Как и при сбросе пароля, ответ не зависит
+// от того, есть ли аккаунт с таким email и подтверждён ли он.
+func (s *EmailVerificationService) Resend(req *models.ResendVerificationRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		s.SendVerification(user)
+	}
+	return nil
+}
+
+// Verify подтверждает email по токену из письма. Ссылка перестаёт работать,
+// если после её отправки пользователь сменил email.
+func (s *EmailVerificationService) Verify(req *models.VerifyEmailRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return err
+	}
+
+	userID, expiresAt, signature, err := parseVerificationToken(req.Token)
+	if err != nil || time.Now().After(expiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(userID, user.Email, expiresAt))) {
+		return ErrInvalidVerificationToken
+	}
+
+	err = s.userRepo.MarkEmailVerified(user.ID, user.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidVerificationToken
+	}
+	return err
+}
+
+// newToken создаёт токен в формате "<user id>.<срок в unix секундах>.<подпись>".
+// Email входит в подпись, но не в сам токен.
+func (s *EmailVerificationService) newToken(userID int, email string, expiresAt time.Time) string {
+	return fmt.Sprintf("%d.%d.%s", userID, expiresAt.Unix(), s.sign(userID, email, expiresAt))
+}
+
+func (s *EmailVerificationService) sign(userID int, email string, expiresAt time.Time) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%d\n%s\n%d", userID, strings.ToLower(email), expiresAt.Unix())
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func parseVerificationToken(token string) (int, time.Time, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, time.Time{}, "", ErrInvalidVerificationToken
+	}
+
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, time.Time{}, "", ErrInvalidVerificationToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, "", ErrInvalidVerificationToken
+	}
+
+	return userID, time.Unix(expires, 0), parts[2], nil
+}
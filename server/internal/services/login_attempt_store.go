@@ -0,0 +1,91 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"tenderness/internal/domain/models"
+)
+
+// LoginAttemptStore хранит счётчики неудачных входов. Реализации:
+// repository.LoginAttemptRepository (Postgres) и MemoryLoginAttemptStore
+// для развёртывания в один экземпляр.
+type LoginAttemptStore interface {
+	// Get возвращает nil, если по ключу нет неудачных попыток
+	Get(key string) (*models.LoginAttempt, error)
+	RegisterFailure(key string, now, resetBefore time.Time) (*models.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+	DeleteStale(before time.Time) (int64, error)
+}
+
+// MemoryLoginAttemptStore хранит счётчики в памяти процесса; после перезапуска они обнуляются
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts: make(map[string]models.LoginAttempt),
+	}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) RegisterFailure(key string, now, resetBefore time.Time) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(resetBefore) {
+		attempt = models.LoginAttempt{Key: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+func (s *MemoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		s.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) DeleteStale(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(before) && (attempt.LockedUntil == nil || attempt.LockedUntil.Before(before)) {
+			delete(s.attempts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
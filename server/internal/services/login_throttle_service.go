@@ -0,0 +1,156 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tenderness/internal/repository"
+)
+
+var ErrLoginLocked = errors.New("too many failed login attempts, try again later")
+
+// LoginLockedError - вход временно заблокирован; RetryAfter - сколько осталось ждать
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// maxLockoutShift ограничивает степень двойки, чтобы длительность блокировки не переполнилась
+const maxLockoutShift = 20
+
+// LoginThrottleService считает неудачные входы по аккаунту и по IP адресу.
+// Когда неудач становится больше порога, ключ блокируется на baseLockout,
+// и каждая следующая неудача удваивает блокировку вплоть до maxLockout.
+// Счётчик обнуляется, если неудач не было дольше window.
+type LoginThrottleService struct {
+	store              LoginAttemptStore
+	userRepo           *repository.UserRepository
+	maxAccountFailures int
+	maxIPFailures      int
+	baseLockout        time.Duration
+	maxLockout         time.Duration
+	window             time.Duration
+	now                func() time.Time
+}
+
+func NewLoginThrottleService(store LoginAttemptStore, userRepo *repository.UserRepository, maxAccountFailures, maxIPFailures int, baseLockout, maxLockout, window time.Duration) *LoginThrottleService {
+	return &LoginThrottleService{
+		store:              store,
+		userRepo:           userRepo,
+		maxAccountFailures: maxAccountFailures,
+		maxIPFailures:      maxIPFailures,
+		baseLockout:        baseLockout,
+		maxLockout:         maxLockout,
+		window:             window,
+		now:                time.Now,
+	}
+}
+
+// LoginAccountKey - ключ счётчика для входа по паролю; считается и для несуществующих
+// email, чтобы по блокировке нельзя было узнать, есть ли аккаунт
+func LoginAccountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginUserKey - ключ счётчика для второго фактора
+func LoginUserKey(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func LoginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check возвращает *LoginLockedError, если заблокирован хотя бы один из ключей
+func (s *LoginThrottleService) Check(accountKey, ipKey string) error {
+	now := s.now()
+
+	var retryAfter time.Duration
+	for _, key := range []string{accountKey, ipKey} {
+		attempt, err := s.store.Get(key)
+		if err != nil {
+			return err
+		}
+		if attempt != nil && attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			retryAfter = max(retryAfter, attempt.LockedUntil.Sub(now))
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RegisterFailure учитывает неудачную попытку и при превышении порога блокирует ключ
+func (s *LoginThrottleService) RegisterFailure(accountKey, ipKey string) error {
+	if err := s.registerFailure(accountKey, s.maxAccountFailures); err != nil {
+		return err
+	}
+	return s.registerFailure(ipKey, s.maxIPFailures)
+}
+
+// Succeeded обнуляет счётчик аккаунта после успешного входа. Счётчик IP не
+// сбрасывается: иначе перебор чужих паролей можно было бы прерывать входом в свой аккаунт.
+func (s *LoginThrottleService) Succeeded(accountKey string) error {
+	return s.store.Reset(accountKey)
+}
+
+// UnlockUser снимает блокировку входа пользователя по паролю и по второму фактору
+func (s *LoginThrottleService) UnlockUser(actorID, userID int) error {
+	user, err := s.userRepo.GetByID(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{LoginAccountKey(user.Email), LoginUserKey(user.ID)} {
+		if err := s.store.Reset(key); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Login unlocked for user %d by user %d", userID, actorID)
+	return nil
+}
+
+// DeleteStale удаляет устаревшие счётчики
+func (s *LoginThrottleService) DeleteStale() error {
+	_, err := s.store.DeleteStale(s.now().Add(-s.window))
+	return err
+}
+
+func (s *LoginThrottleService) registerFailure(key string, maxFailures int) error {
+	now := s.now()
+	attempt, err := s.store.RegisterFailure(key, now, now.Add(-s.window))
+	if err != nil {
+		return err
+	}
+	if attempt.Failures < maxFailures {
+		return nil
+	}
+
+	lockout := s.baseLockout << min(attempt.Failures-maxFailures, maxLockoutShift)
+	if lockout > s.maxLockout || lockout <= 0 {
+		lockout = s.maxLockout
+	}
+
+	if err := s.store.Lock(key, now.Add(lockout)); err != nil {
+		return err
+	}
+	log.Printf("Login locked for %s for %s after %d failed attempts", key, lockout, attempt.Failures)
+	return nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestThrottle - троттлинг на памяти с часами, которые двигает тест
+func newTestThrottle(maxFailures int, baseLockout, maxLockout time.Duration) (*LoginThrottleService, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewLoginThrottleService(NewMemoryLoginAttemptStore(), nil, maxFailures, 1000, baseLockout, maxLockout, time.Hour)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func retryAfter(t *testing.T, s *LoginThrottleService, key string) time.Duration {
+	t.Helper()
+
+	err := s.Check(key, LoginIPKey("192.0.2.1"))
+	if err == nil {
+		return 0
+	}
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Check: %v", err)
+	}
+	return locked.RetryAfter
+}
+
+func TestLoginLockoutDoublesUpToMax(t *testing.T) {
+	s, _ := newTestThrottle(3, time.Minute, 10*time.Minute)
+	key := LoginAccountKey("User@Example.com ")
+
+	// Блокировка после третьей неудачи, дальше каждая неудача удваивает её до maxLockout
+	want := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i, lockout := range want {
+		if err := s.RegisterFailure(key, LoginIPKey("192.0.2.1")); err != nil {
+			t.Fatal(err)
+		}
+		if got := retryAfter(t, s, key); got != lockout {
+			t.Fatalf("after %d failures lockout = %s, want %s", i+1, got, lockout)
+		}
+	}
+
+	// Ключ не зависит от регистра и пробелов в email
+	if got := retryAfter(t, s, LoginAccountKey("user@example.com")); got != 10*time.Minute {
+		t.Fatalf("lockout by normalized email = %s, want 10m", got)
+	}
+}
+
+func TestLoginLockoutShiftDoesNotOverflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		failures int
+	}{
+		// Степень двойки ограничена maxLockoutShift
+		{"many failures", time.Second, 100},
+		// base << maxLockoutShift переполняет Duration
+		{"overflow", 1 << 50, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestThrottle(1, tt.base, 24*time.Hour)
+			key := LoginUserKey(1)
+			for range tt.failures {
+				if err := s.RegisterFailure(key, LoginIPKey("192.0.2.1")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := retryAfter(t, s, key); got != 24*time.Hour {
+				t.Fatalf("lockout = %s, want maxLockout", got)
+			}
+		})
+	}
+}
+
+func TestLoginFailuresResetAfterWindow(t *testing.T) {
+	s, now := newTestThrottle(3, time.Minute, time.Hour)
+	key := LoginAccountKey("user@example.com")
+	ipKey := LoginIPKey("192.0.2.1")
+
+	for range 3 {
+		if err := s.RegisterFailure(key, ipKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := retryAfter(t, s, key); got != time.Minute {
+		t.Fatalf("lockout = %s, want 1m", got)
+	}
+
+	// Блокировка истекла, но неудачи ещё в окне: следующая снова блокирует, уже на 2 минуты
+	*now = now.Add(2 * time.Minute)
+	if got := retryAfter(t, s, key); got != 0 {
+		t.Fatalf("lockout after it expired = %s, want none", got)
+	}
+	if err := s.RegisterFailure(key, ipKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := retryAfter(t, s, key); got != 2*time.Minute {
+		t.Fatalf("lockout within window = %s, want 2m", got)
+	}
+
+	// Без неудач дольше окна счётчик начинается заново
+	*now = now.Add(time.Hour + time.Second)
+	if err := s.RegisterFailure(key, ipKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := retryAfter(t, s, key); got != 0 {
+		t.Fatalf("lockout after the window = %s, want none", got)
+	}
+	attempt, err := s.store.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 1 {
+		t.Fatalf("failures after the window = %d, want 1", attempt.Failures)
+	}
+}
+
+func TestLoginSucceededResetsOnlyAccount(t *testing.T) {
+	s, _ := newTestThrottle(2, time.Minute, time.Hour)
+	s.maxIPFailures = 2
+	key := LoginAccountKey("user@example.com")
+	ipKey := LoginIPKey("192.0.2.1")
+
+	for range 2 {
+		if err := s.RegisterFailure(key, ipKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Succeeded(key); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempt, _ := s.store.Get(key); attempt != nil {
+		t.Fatalf("account counter after success = %+v, want none", attempt)
+	}
+	if err := s.Check(LoginAccountKey("other@example.com"), ipKey); !errors.Is(err, ErrLoginLocked) {
+		t.Fatalf("IP lock after a successful login: err = %v, want ErrLoginLocked", err)
+	}
+}
+
+func TestMemoryLoginAttemptStore(t *testing.T) {
+	store := NewMemoryLoginAttemptStore()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if attempt, err := store.Get("missing"); attempt != nil || err != nil {
+		t.Fatalf("Get of a missing key = %+v, %v", attempt, err)
+	}
+	// Блокировка ключа без неудач ничего не создаёт
+	if err := store.Lock("missing", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if attempt, _ := store.Get("missing"); attempt != nil {
+		t.Fatalf("Lock created a counter: %+v", attempt)
+	}
+
+	for _, key := range []string{"stale", "locked", "fresh"} {
+		if _, err := store.RegisterFailure(key, now.Add(-2*time.Hour), now.Add(-3*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Lock("locked", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	attempt, err := store.RegisterFailure("fresh", now, now.Add(-3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 2 || !attempt.LastFailureAt.Equal(now) {
+		t.Fatalf("RegisterFailure within window = %+v, want 2 failures at now", attempt)
+	}
+
+	// Возвращается копия: изменения вызывающего не попадают в хранилище
+	attempt.Failures = 100
+	if stored, _ := store.Get("fresh"); stored.Failures != 2 {
+		t.Fatalf("stored failures = %d, want 2", stored.Failures)
+	}
+
+	// Начало нового окна сохраняет действующую блокировку
+	attempt, err = store.RegisterFailure("locked", now, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Failures != 1 || attempt.LockedUntil == nil {
+		t.Fatalf("RegisterFailure after the window = %+v, want 1 failure and the lock kept", attempt)
+	}
+
+	deleted, err := store.DeleteStale(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("DeleteStale deleted %d counters, want 1", deleted)
+	}
+	for key, kept := range map[string]bool{"stale": false, "locked": true, "fresh": true} {
+		if attempt, _ := store.Get(key); (attempt != nil) != kept {
+			t.Errorf("counter %s kept = %v, want %v", key, attempt != nil, kept)
+		}
+	}
+}
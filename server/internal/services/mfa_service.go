@@ -0,0 +1,328 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/repository"
+	"tenderness/internal/totp"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication enrollment has not been started")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew - сколько соседних шагов TOTP принимается из-за расхождения часов
+	totpSkew = 1
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAService управляет вторым фактором входа: TOTP и одноразовыми кодами восстановления.
+// Коды восстановления хранятся только в виде SHA-256.
+type MFAService struct {
+	tx           *repository.TxManager
+	mfaRepo      *repository.MFARepository
+	userRepo     *repository.UserRepository
+	tokens       *TokenService
+	revocations  *RevocationService
+	jwt          *middleware.JWTMiddleware
+	throttle     *LoginThrottleService
+	issuer       string
+	challengeTTL time.Duration
+	validator    *validator.Validate
+}
+
+func NewMFAService(tx *repository.TxManager, mfaRepo *repository.MFARepository, userRepo *repository.UserRepository, tokens *TokenService, revocations *RevocationService, jwt *middleware.JWTMiddleware, throttle *LoginThrottleService, issuer string, challengeTTL time.Duration) *MFAService {
+	return &MFAService{
+		tx:           tx,
+		mfaRepo:      mfaRepo,
+		userRepo:     userRepo,
+		tokens:       tokens,
+		revocations:  revocations,
+		jwt:          jwt,
+		throttle:     throttle,
+		issuer:       issuer,
+		challengeTTL: challengeTTL,
+		validator:    newValidator(),
+	}
+}
+
+func (s *MFAService) GetStatus(userID int) (*models.MFAStatus, error) {
+	status := &models.MFAStatus{}
+
+	userTOTP, err := s.mfaRepo.GetTOTP(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.TOTPEnabled = userTOTP.EnabledAt != nil
+	status.EnabledAt = userTOTP.EnabledAt
+	if status.TOTPEnabled {
+		status.RecoveryCodesCount, err = s.mfaRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll создаёт новый TOTP секрет. Второй фактор включается только после
+// подтверждения кодом из приложения.
+func (s *MFAService) Enroll(userID int) (*models.TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.mfaRepo.GetTOTP(userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if existing != nil && existing.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SaveTOTPSecret(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm включает TOTP и выдаёт коды восстановления. Коды показываются только один раз.
+func (s *MFAService) Confirm(userID int, req *models.MFACodeRequest) (*models.RecoveryCodesResponse, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		userTOTP, err := s.mfaRepo.GetTOTPForUpdate(tx, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMFANotEnrolled
+		}
+		if err != nil {
+			return err
+		}
+		if userTOTP.EnabledAt != nil {
+			return ErrMFAAlreadyEnabled
+		}
+
+		step, ok := totp.Validate(userTOTP.Secret, req.Code, time.Now(), totpSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		if err := s.mfaRepo.EnableTOTP(tx, userID, step); err != nil {
+			return err
+		}
+
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable отключает TOTP; нужен действующий код из приложения или код восстановления
+func (s *MFAService) Disable(userID int, req *models.MFACodeRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return err
+	}
+
+	return s.tx.WithTx(func(tx *sqlx.Tx) error {
+		if err := s.verifyCode(tx, userID, req.Code); err != nil {
+			return err
+		}
+		return s.mfaRepo.DeleteTOTP(tx, userID)
+	})
+}
+
+// RegenerateRecoveryCodes заменяет все коды восстановления новыми
+func (s *MFAService) RegenerateRecoveryCodes(userID int, req *models.MFACodeRequest) (*models.RecoveryCodesResponse, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		if err := s.verifyCode(tx, userID, req.Code); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Challenge возвращает промежуточный токен входа, если у пользователя включён
+// второй фактор, и nil, если нет
+func (s *MFAService) Challenge(userID int) (*models.MFAChallenge, error) {
+	userTOTP, err := s.mfaRepo.GetTOTP(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userTOTP.EnabledAt == nil {
+		return nil, nil
+	}
+
+	token, err := s.jwt.GenerateMFAToken(userID, s.challengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(s.challengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteLogin проверяет код второго фактора и выдаёт пару токенов.
+// Промежуточный токен после успешного входа отзывается. Неверные коды
+// учитываются так же, как неверные пароли.
+func (s *MFAService) CompleteLogin(req *models.MFALoginRequest, client models.ClientInfo) (*models.AuthResponse, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	claims, err := s.jwt.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	userKey, ipKey := LoginUserKey(claims.UserID), LoginIPKey(client.IP)
+	if err := s.throttle.Check(userKey, ipKey); err != nil {
+		return nil, err
+	}
+
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		return s.verifyCode(tx, claims.UserID, req.Code)
+	})
+	if errors.Is(err, ErrMFANotEnabled) {
+		// Второй фактор отключили, пока шёл вход
+		return nil, ErrInvalidMFAToken
+	}
+	if errors.Is(err, ErrInvalidMFACode) {
+		if err := s.throttle.RegisterFailure(userKey, ipKey); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidMFACode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.throttle.Succeeded(userKey); err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+
+	response, err := s.tokens.NewAuthResponseForUser(claims.UserID, client)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidMFAToken
+	}
+	return response, err
+}
+
+// verifyCode принимает код TOTP (каждый не больше одного раза) или неиспользованный код восстановления
+func (s *MFAService) verifyCode(tx *sqlx.Tx, userID int, code string) error {
+	userTOTP, err := s.mfaRepo.GetTOTPForUpdate(tx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if userTOTP.EnabledAt == nil {
+		return ErrMFANotEnabled
+	}
+
+	if step, ok := totp.Validate(userTOTP.Secret, code, time.Now(), totpSkew); ok {
+		if step <= userTOTP.LastUsedStep {
+			return ErrInvalidMFACode
+		}
+		return s.mfaRepo.SetLastUsedStep(tx, userID, step)
+	}
+
+	err = s.mfaRepo.UseRecoveryCode(tx, userID, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidMFACode
+	}
+	return err
+}
+
+func (s *MFAService) replaceRecoveryCodes(tx *sqlx.Tx, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(tx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCode создаёт код вида "abcde-fghij" (50 бит случайности)
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode позволяет вводить код без дефиса и в любом регистре
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
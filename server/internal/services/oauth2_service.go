@@ -1,289 +1,413 @@
 package services
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"tenderness/internal/domain/models"
-	"tenderness/internal/middleware"
+	"tenderness/internal/oauth"
 	"tenderness/internal/repository"
 
+	"github.com/jmoiron/sqlx"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
-type OAuth2Service struct {
-	userRepo *repository.UserRepository
-	jwt      *middleware.JWTMiddleware
+var (
+	ErrInvalidOAuthState  = errors.New("invalid or expired oauth state")
+	ErrOAuthNotLinked     = errors.New("provider is not linked to this account")
+	ErrOAuthAccountTaken  = errors.New("this provider account is already linked to another user")
+	ErrOAuthAlreadyLinked = errors.New("another account of this provider is already linked")
+	ErrLastLoginMethod    = errors.New("cannot unlink the last login method, set a password first")
+	ErrOAuthLinkRequired  = errors.New("an account with this email already exists, log in to it and confirm linking")
+	ErrInvalidLinkToken   = errors.New("invalid or expired link token")
+	ErrAccountDeactivated = errors.New("user account is deactivated")
+)
+
+// Политика привязки аккаунта провайдера к существующему аккаунту с тем же email
+const (
+	// OAuthLinkVerified - привязывать автоматически, если email подтверждён и провайдером, и в аккаунте
+	OAuthLinkVerified = "verified"
+	// OAuthLinkConfirm - всегда требовать входа в аккаунт и подтверждения привязки
+	OAuthLinkConfirm = "confirm"
+)
+
+// OAuthLinkRequiredError - вход через провайдера не выполнен: аккаунт с тем же email
+// уже есть, и привязку должен подтвердить его владелец после входа. LinkToken
+// остаётся в браузере, начавшем вход, и не передаётся в адресе.
+type OAuthLinkRequiredError struct {
+	LinkToken string
+	Provider  string
+	ExpiresAt time.Time
+}
+
+func (e *OAuthLinkRequiredError) Error() string {
+	return ErrOAuthLinkRequired.Error()
 }
 
-type GoogleUserInfo struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	Name          string `json:"name"`
-	GivenName     string `json:"given_name"`
-	FamilyName    string `json:"family_name"`
-	Picture       string `json:"picture"`
-	VerifiedEmail bool   `json:"verified_email"`
+func (e *OAuthLinkRequiredError) Unwrap() error {
+	return ErrOAuthLinkRequired
 }
 
-type GitHubUserInfo struct {
-	ID        int    `json:"id"`
-	Login     string `json:"login"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	AvatarURL string `json:"avatar_url"`
+type OAuth2Service struct {
+	tx           *repository.TxManager
+	userRepo     *repository.UserRepository
+	identityRepo *repository.UserIdentityRepository
+	tokens       *TokenService
+	verification *EmailVerificationService
+	mfa          *MFAService
+	providers    *oauth.Registry
+	stateSecret  []byte
+	stateTTL     time.Duration
+	linkPolicy   string
+	linkTTL      time.Duration
 }
 
-func NewOAuth2Service(userRepo *repository.UserRepository, jwt *middleware.JWTMiddleware) *OAuth2Service {
+func NewOAuth2Service(tx *repository.TxManager, userRepo *repository.UserRepository, identityRepo *repository.UserIdentityRepository, tokens *TokenService, verification *EmailVerificationService, mfa *MFAService, providers *oauth.Registry, stateSecret string, stateTTL time.Duration, linkPolicy string, linkTTL time.Duration) *OAuth2Service {
 	return &OAuth2Service{
-		userRepo: userRepo,
-		jwt:      jwt,
+		tx:           tx,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		tokens:       tokens,
+		verification: verification,
+		mfa:          mfa,
+		providers:    providers,
+		stateSecret:  []byte(stateSecret),
+		stateTTL:     stateTTL,
+		linkPolicy:   linkPolicy,
+		linkTTL:      linkTTL,
 	}
 }
 
-// Google OAuth2 configuration
-var googleOAuth2Config = &oauth2.Config{
-	ClientID:     getEnv("GOOGLE_CLIENT_ID", "your-google-client-id"),
-	ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", "your-google-client-secret"),
-	RedirectURL:  "http://localhost/api/auth/google/callback",
-	Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-	Endpoint:     google.Endpoint,
+// Providers возвращает имена настроенных провайдеров
+func (s *OAuth2Service) Providers() []string {
+	return s.providers.Names()
 }
 
-// GitHub OAuth2 configuration
-var githubOAuth2Config = &oauth2.Config{
-	ClientID:     getEnv("GITHUB_CLIENT_ID", "your-github-client-id"),
-	ClientSecret: getEnv("GITHUB_CLIENT_SECRET", "your-github-client-secret"),
-	RedirectURL:  "http://localhost/api/auth/github/callback",
-	Scopes:       []string{"user:email"},
-	Endpoint: oauth2.Endpoint{
-		AuthURL:  "https://github.com/login/oauth/authorize",
-		TokenURL: "https://github.com/login/oauth/access_token",
-	},
-}
+// BeginAuth начинает вход через провайдера: возвращает адрес авторизации
+// и параметры, которые нужно сохранить до callback
+func (s *OAuth2Service) BeginAuth(provider string) (string, *models.OAuthFlow, error) {
+	p, err := s.providers.Get(provider)
+	if err != nil {
+		return "", nil, err
+	}
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	state, err := s.GenerateState()
+	if err != nil {
+		return "", nil, err
 	}
-	return defaultValue
-}
 
-func (s *OAuth2Service) GetAuthURL(provider, state string) (string, error) {
-	switch provider {
-	case "google":
-		return googleOAuth2Config.AuthCodeURL(state), nil
-	case "github":
-		return githubOAuth2Config.AuthCodeURL(state), nil
-	default:
-		return "", fmt.Errorf("unsupported provider: %s", provider)
+	flow := &models.OAuthFlow{
+		Provider:     provider,
+		State:        state,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ExpiresAt:    time.Now().Add(s.stateTTL).Unix(),
 	}
+
+	// Провайдеры OpenID Connect выдают id_token, в котором проверяется nonce
+	if p.UsesNonce() {
+		flow.Nonce, err = s.GenerateState()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	authURL, err := p.AuthCodeURL(context.Background(), authRequest(flow))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return authURL, flow, nil
 }
 
-func (s *OAuth2Service) ExchangeCode(provider, code, state string) (*models.AuthResponse, error) {
-	switch provider {
-	case "google":
-		return s.handleGoogleCallback(code, state)
-	case "github":
-		return s.handleGitHubCallback(code, state)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+// Назначения подписанных значений: подпись одного назначения не подходит для другого
+const (
+	signedOAuthFlow = "flow"
+	signedOAuthLink = "link"
+)
+
+// EncodeFlow подписывает параметры входа для хранения в cookie
+func (s *OAuth2Service) EncodeFlow(flow *models.OAuthFlow) (string, error) {
+	return s.encodeSigned(signedOAuthFlow, flow)
+}
+
+// VerifyFlow проверяет подпись и срок cookie и то, что она выдана для этого
+// провайдера и этого state
+func (s *OAuth2Service) VerifyFlow(value, provider, state string) (*models.OAuthFlow, error) {
+	var flow models.OAuthFlow
+	if !s.decodeSigned(signedOAuthFlow, value, &flow) {
+		return nil, ErrInvalidOAuthState
 	}
+
+	if flow.Provider != provider || time.Now().Unix() > flow.ExpiresAt ||
+		subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, ErrInvalidOAuthState
+	}
+
+	return &flow, nil
 }
 
-func (s *OAuth2Service) handleGoogleCallback(code, state string) (*models.AuthResponse, error) {
-	// Exchange authorization code for token
-	token, err := googleOAuth2Config.Exchange(oauth2.NoContext, code)
+// encodeSigned сериализует значение в вид "<json в base64>.<подпись>"
+func (s *OAuth2Service) encodeSigned(purpose string, v any) (string, error) {
+	payload, err := json.Marshal(v)
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange token: %w", err)
+		return "", err
 	}
 
-	// Get user info from Google
-	client := googleOAuth2Config.Client(oauth2.NoContext, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(purpose, encoded), nil
+}
+
+func (s *OAuth2Service) decodeSigned(purpose, value string, v any) bool {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(purpose, encoded))) {
+		return false
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return false
 	}
+	return json.Unmarshal(payload, v) == nil
+}
 
-	var userInfo GoogleUserInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse user info: %w", err)
-	}
+func (s *OAuth2Service) sign(purpose, encoded string) string {
+	mac := hmac.New(sha256.New, s.stateSecret)
+	mac.Write([]byte(purpose + "." + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
 
-	// Check if user exists
-	user, err := s.userRepo.GetByGoogleID(userInfo.ID)
+// ExchangeCode завершает вход через провайдера. Как и при входе по паролю, при
+// включённом втором факторе вместо токенов возвращается MFAChallenge.
+func (s *OAuth2Service) ExchangeCode(provider, code string, flow *models.OAuthFlow, client models.ClientInfo) (*models.AuthResponse, *models.MFAChallenge, error) {
+	identity, err := s.exchange(provider, code, flow)
 	if err != nil {
-		// User doesn't exist, create new user
-		names := strings.Split(userInfo.Name, " ")
-		firstName := names[0]
-		lastName := ""
-		if len(names) > 1 {
-			lastName = strings.Join(names[1:], " ")
-		}
+		return nil, nil, err
+	}
 
-		newUser := &models.User{
-			Email:        userInfo.Email,
-			FirstName:    firstName,
-			LastName:     lastName,
-			AvatarURL:    userInfo.Picture,
-			GoogleID:     userInfo.ID,
-			AuthProvider: "google",
-			IsActive:     true,
+	user, err := s.userRepo.GetByOAuthID(provider, identity.Subject)
+	switch {
+	case err == nil:
+		if err := s.identityRepo.UpdateProfile(userIdentity(user.ID, provider, identity)); err != nil {
+			return nil, nil, fmt.Errorf("failed to update %s profile: %w", provider, err)
 		}
-
-		createdUser, err := s.userRepo.CreateOAuth(newUser)
+	case errors.Is(err, sql.ErrNoRows):
+		user, err = s.signUp(provider, identity)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
+			return nil, nil, err
 		}
+	default:
+		return nil, nil, err
+	}
+
+	if !user.IsActive {
+		return nil, nil, ErrAccountDeactivated
+	}
 
-		user = createdUser
+	if err := s.trustProviderEmail(user, identity.Email, identity.EmailVerified); err != nil {
+		return nil, nil, err
 	}
 
-	// Generate JWT token
-	jwtToken, err := s.jwt.GenerateToken(user.ID, user.Email)
+	challenge, err := s.mfa.Challenge(user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
+	}
+
+	roles, err := s.userRepo.GetRoles(user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load roles: %w", err)
 	}
 
 	userResponse := &models.UserResponse{
-		ID:           user.ID,
-		Email:        user.Email,
-		FirstName:    user.FirstName,
-		LastName:     user.LastName,
-		Phone:        user.Phone,
-		IsActive:     user.IsActive,
-		CreatedAt:    user.CreatedAt,
-		AvatarURL:    user.AvatarURL,
-		AuthProvider: user.AuthProvider,
-	}
-
-	return &models.AuthResponse{
-		User:  *userResponse,
-		Token: jwtToken,
-	}, nil
+		ID:            user.ID,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Phone:         user.Phone,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Roles:         roles,
+		CreatedAt:     user.CreatedAt,
+		AvatarURL:     user.AvatarURL,
+		AuthProvider:  user.AuthProvider,
+	}
+
+	response, err := s.tokens.NewAuthResponse(*userResponse, client)
+	return response, nil, err
 }
 
-func (s *OAuth2Service) handleGitHubCallback(code, state string) (*models.AuthResponse, error) {
-	// Exchange authorization code for token
-	token, err := githubOAuth2Config.Exchange(oauth2.NoContext, code)
+// exchange обменивает код на сведения о пользователе у провайдера
+func (s *OAuth2Service) exchange(provider, code string, flow *models.OAuthFlow) (*oauth.Identity, error) {
+	p, err := s.providers.Get(provider)
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange token: %w", err)
+		return nil, err
 	}
 
-	// Get user info from GitHub
-	client := githubOAuth2Config.Client(oauth2.NoContext, token)
-	resp, err := client.Get("https://api.github.com/user")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+	identity, err := p.Exchange(context.Background(), code, authRequest(flow))
+	if errors.Is(err, oauth.ErrNonceMismatch) {
+		return nil, ErrInvalidOAuthState
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("%s: %w", provider, err)
 	}
 
-	var userInfo GitHubUserInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse user info: %w", err)
+	return identity, nil
+}
+
+// signUp создаёт аккаунт для нового пользователя провайдера. Если аккаунт с тем же
+// email уже есть, провайдер привязывается к нему автоматически или после
+// подтверждения - по политике linkPolicy. Email удалённого аккаунта занят, вход с
+// ним отклоняется.
+func (s *OAuth2Service) signUp(provider string, identity *oauth.Identity) (*models.User, error) {
+	if identity.Email == "" {
+		return s.createOAuthUser(provider, identity)
 	}
 
-	// Get user email (requires separate API call)
-	emailResp, err := client.Get("https://api.github.com/user/emails")
+	existing, err := s.userRepo.GetByEmailIncludingInactive(identity.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, createErr := s.createOAuthUser(provider, identity)
+		if !repository.IsUniqueViolation(createErr) {
+			return user, createErr
+		}
+		// Аккаунт создан параллельным запросом между проверкой email и вставкой:
+		// это тот же вход через провайдера или регистрация с тем же email
+		if user, err := s.userRepo.GetByOAuthID(provider, identity.Subject); err == nil {
+			return user, nil
+		}
+		existing, err = s.userRepo.GetByEmailIncludingInactive(identity.Email)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user emails: %w", err)
+		return nil, err
+	}
+	if !existing.IsActive {
+		return nil, ErrAccountDeactivated
 	}
-	defer emailResp.Body.Close()
 
-	emailBody, err := io.ReadAll(emailResp.Body)
+	if s.canAutoLink(existing, identity) {
+		if err := s.link(userIdentity(existing.ID, provider, identity)); err != nil {
+			return nil, err
+		}
+		return s.userRepo.GetByOAuthID(provider, identity.Subject)
+	}
+
+	expiresAt := time.Now().Add(s.linkTTL)
+	linkToken, err := s.encodeSigned(signedOAuthLink, &models.PendingOAuthLink{
+		UserID:        existing.ID,
+		Provider:      provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+		AvatarURL:     identity.AvatarURL,
+		ExpiresAt:     expiresAt.Unix(),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read email response: %w", err)
+		return nil, err
 	}
 
-	var emails []struct {
-		Email   string `json:"email"`
-		Primary bool   `json:"primary"`
+	return nil, &OAuthLinkRequiredError{
+		LinkToken: linkToken,
+		Provider:  provider,
+		ExpiresAt: expiresAt,
 	}
-	if err := json.Unmarshal(emailBody, &emails); err != nil {
-		return nil, fmt.Errorf("failed to parse emails: %w", err)
+}
+
+// canAutoLink разрешает автоматическую привязку, только если email подтверждён и
+// провайдером, и в самом аккаунте. Иначе аккаунт, заранее зарегистрированный
+// злоумышленником на чужой email, получил бы вход владельца email через провайдера.
+func (s *OAuth2Service) canAutoLink(user *models.User, identity *oauth.Identity) bool {
+	return s.linkPolicy == OAuthLinkVerified && identity.EmailVerified && user.EmailVerifiedAt != nil &&
+		strings.EqualFold(user.Email, identity.Email)
+}
+
+func (s *OAuth2Service) createOAuthUser(provider string, identity *oauth.Identity) (*models.User, error) {
+	firstName, lastName := splitName(identity)
+
+	newUser := &models.User{
+		Email:        identity.Email,
+		FirstName:    firstName,
+		LastName:     lastName,
+		AvatarURL:    identity.AvatarURL,
+		AuthProvider: provider,
+		IsActive:     true,
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		newUser.EmailVerifiedAt = &now
 	}
 
-	var primaryEmail string
-	for _, email := range emails {
-		if email.Primary {
-			primaryEmail = email.Email
-			break
+	var createdUser *models.User
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		var err error
+		createdUser, err = s.userRepo.CreateOAuth(tx, newUser)
+		if err != nil {
+			return err
 		}
+		return s.identityRepo.Create(tx, userIdentity(createdUser.ID, provider, identity))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	if primaryEmail == "" {
-		return nil, errors.New("no primary email found")
-	}
+	return createdUser, nil
+}
 
-	// Check if user exists
-	user, err := s.userRepo.GetByGithubID(fmt.Sprintf("%d", userInfo.ID))
-	if err != nil {
-		// User doesn't exist, create new user
-		names := strings.Split(userInfo.Name, " ")
-		firstName := names[0]
-		lastName := ""
-		if len(names) > 1 {
-			lastName = strings.Join(names[1:], " ")
-		}
+// userIdentity - запись о привязке со снимком профиля у провайдера
+func userIdentity(userID int, provider string, identity *oauth.Identity) *models.UserIdentity {
+	return &models.UserIdentity{
+		UserID:        userID,
+		Provider:      provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+		AvatarURL:     identity.AvatarURL,
+	}
+}
 
-		newUser := &models.User{
-			Email:        primaryEmail,
-			FirstName:    firstName,
-			LastName:     lastName,
-			AvatarURL:    userInfo.AvatarURL,
-			GithubID:     fmt.Sprintf("%d", userInfo.ID),
-			AuthProvider: "github",
-			IsActive:     true,
-		}
+// splitName берёт имя и фамилию из отдельных claims, а если их нет - делит полное имя
+func splitName(identity *oauth.Identity) (string, string) {
+	if identity.GivenName != "" {
+		return identity.GivenName, identity.FamilyName
+	}
 
-		createdUser, err := s.userRepo.CreateOAuth(newUser)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
-		}
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(identity.Name), " ")
+	return firstName, strings.TrimSpace(lastName)
+}
 
-		user = createdUser
+func authRequest(flow *models.OAuthFlow) oauth.AuthRequest {
+	return oauth.AuthRequest{
+		State:        flow.State,
+		CodeVerifier: flow.CodeVerifier,
+		Nonce:        flow.Nonce,
 	}
+}
 
-	// Generate JWT token
-	jwtToken, err := s.jwt.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+// trustProviderEmail отмечает email подтверждённым, если провайдер подтвердил тот же
+// адрес, и проверяет, разрешён ли вход
+func (s *OAuth2Service) trustProviderEmail(user *models.User, providerEmail string, providerVerified bool) error {
+	if user.EmailVerifiedAt == nil && providerVerified && strings.EqualFold(user.Email, providerEmail) {
+		if err := s.userRepo.MarkEmailVerified(user.ID, user.Email); err != nil {
+			return fmt.Errorf("failed to mark email verified: %w", err)
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
 	}
 
-	userResponse := &models.UserResponse{
-		ID:           user.ID,
-		Email:        user.Email,
-		FirstName:    user.FirstName,
-		LastName:     user.LastName,
-		Phone:        user.Phone,
-		IsActive:     user.IsActive,
-		CreatedAt:    user.CreatedAt,
-		AvatarURL:    user.AvatarURL,
-		AuthProvider: user.AuthProvider,
-	}
-
-	return &models.AuthResponse{
-		User:  *userResponse,
-		Token: jwtToken,
-	}, nil
+	return s.verification.CheckLogin(user)
 }
 
 func (s *OAuth2Service) GenerateState() (string, error) {
@@ -295,99 +419,145 @@ func (s *OAuth2Service) GenerateState() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-func (s *OAuth2Service) LinkOAuthToAccount(userID int, provider, code string) error {
-	var user *models.User
-	var err error
-
-	switch provider {
-	case "google":
-		user, err = s.handleGoogleUserCreation(code)
-	case "github":
-		user, err = s.handleGitHubUserCreation(code)
-	default:
-		return fmt.Errorf("unsupported provider: %s", provider)
-	}
-
+// LinkOAuthToAccount привязывает аккаунт провайдера к вошедшему пользователю
+func (s *OAuth2Service) LinkOAuthToAccount(userID int, provider, code string, flow *models.OAuthFlow) error {
+	identity, err := s.exchange(provider, code, flow)
 	if err != nil {
 		return fmt.Errorf("failed to get %s user: %w", provider, err)
 	}
 
-	// Link OAuth to existing user
-	return s.userRepo.LinkOAuth(userID, provider, user.GoogleID, user.GithubID, user.AvatarURL)
+	return s.link(userIdentity(userID, provider, identity))
 }
 
-func (s *OAuth2Service) handleGoogleUserCreation(code string) (*models.User, error) {
-	token, err := googleOAuth2Config.Exchange(oauth2.NoContext, code)
-	if err != nil {
-		return nil, fmt.Errorf("failed to exchange token: %w", err)
-	}
+// ConfirmLink привязывает аккаунт провайдера, вход через который вернул link_token.
+// Токен принимается только от пользователя, для аккаунта которого он выдан, и
+// только из cookie браузера, в котором начинался вход через провайдера.
+func (s *OAuth2Service) ConfirmLink(userID int, linkToken string) error {
+	var pending models.PendingOAuthLink
+	if !s.decodeSigned(signedOAuthLink, linkToken, &pending) ||
+		time.Now().Unix() > pending.ExpiresAt || pending.UserID != userID {
+		return ErrInvalidLinkToken
+	}
+
+	return s.link(&models.UserIdentity{
+		UserID:        userID,
+		Provider:      pending.Provider,
+		Subject:       pending.Subject,
+		Email:         pending.Email,
+		EmailVerified: pending.EmailVerified,
+		Name:          pending.Name,
+		AvatarURL:     pending.AvatarURL,
+	})
+}
 
-	client := googleOAuth2Config.Client(oauth2.NoContext, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+// link привязывает аккаунт провайдера к пользователю. Аккаунт провайдера, уже
+// привязанный к другому пользователю, не перепривязывается, а второй аккаунт
+// того же провайдера к пользователю не привязывается.
+func (s *OAuth2Service) link(linked *models.UserIdentity) error {
+	existing, err := s.identityRepo.Get(linked.Provider, linked.Subject)
+	if err == nil {
+		if existing.UserID != linked.UserID {
+			return ErrOAuthAccountTaken
+		}
+		return s.identityRepo.UpdateProfile(linked)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
 	}
 
-	var userInfo GoogleUserInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse user info: %w", err)
-	}
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		if _, err := s.userRepo.GetByIDForUpdate(tx, linked.UserID); err != nil {
+			return err
+		}
 
-	names := strings.Split(userInfo.Name, " ")
-	firstName := names[0]
-	lastName := ""
-	if len(names) > 1 {
-		lastName = strings.Join(names[1:], " ")
-	}
+		identities, err := s.identityRepo.ListByUserTx(tx, linked.UserID)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(identities, func(i models.UserIdentity) bool { return i.Provider == linked.Provider }) {
+			return ErrOAuthAlreadyLinked
+		}
 
-	return &models.User{
-		GoogleID:  userInfo.ID,
-		AvatarURL: userInfo.Picture,
-		FirstName: firstName,
-		LastName:  lastName,
-	}, nil
+		if err := s.identityRepo.Create(tx, linked); err != nil {
+			return err
+		}
+		return s.userRepo.SetAvatarIfEmpty(tx, linked.UserID, linked.AvatarURL)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
 }
 
-func (s *OAuth2Service) handleGitHubUserCreation(code string) (*models.User, error) {
-	token, err := githubOAuth2Config.Exchange(oauth2.NoContext, code)
-	if err != nil {
-		return nil, fmt.Errorf("failed to exchange token: %w", err)
+// GetLinkedAccounts возвращает способы входа пользователя
+func (s *OAuth2Service) GetLinkedAccounts(userID int) (*models.LinkedAccounts, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
 	}
-
-	client := githubOAuth2Config.Client(oauth2.NoContext, token)
-	resp, err := client.Get("https://api.github.com/user")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	identities, err := s.identityRepo.ListByUser(userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	var userInfo GitHubUserInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse user info: %w", err)
-	}
+	return &models.LinkedAccounts{
+		HasPassword: user.Password != "",
+		Identities:  identities,
+	}, nil
+}
 
-	names := strings.Split(userInfo.Name, " ")
-	firstName := names[0]
-	lastName := ""
-	if len(names) > 1 {
-		lastName = strings.Join(names[1:], " ")
-	}
+// UnlinkOAuth отвязывает провайдера от аккаунта и возвращает оставшиеся способы входа.
+// Последний способ входа у пользователя без пароля отвязать нельзя: в аккаунт
+// было бы не войти. Если отвязан основной способ входа (auth_provider), основным
+// становится пароль, а без него - оставшийся провайдер.
+func (s *OAuth2Service) UnlinkOAuth(userID int, provider string) (*models.LinkedAccounts, error) {
+	var accounts *models.LinkedAccounts
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		user, err := s.userRepo.GetByIDForUpdate(tx, userID)
+		if err != nil {
+			return err
+		}
 
-	return &models.User{
-		GithubID:  fmt.Sprintf("%d", userInfo.ID),
-		AvatarURL: userInfo.AvatarURL,
-		FirstName: firstName,
-		LastName:  lastName,
-	}, nil
+		identities, err := s.identityRepo.ListByUserTx(tx, userID)
+		if err != nil {
+			return err
+		}
+		remaining := slices.DeleteFunc(slices.Clone(identities), func(i models.UserIdentity) bool { return i.Provider == provider })
+		if len(remaining) == len(identities) {
+			return ErrOAuthNotLinked
+		}
+
+		hasPassword := user.Password != ""
+		if !hasPassword && len(remaining) == 0 {
+			return ErrLastLoginMethod
+		}
+
+		if err := s.identityRepo.Delete(tx, userID, provider); err != nil {
+			return err
+		}
+
+		if user.AuthProvider == provider || user.AuthProvider == "" {
+			authProvider := "email"
+			if !hasPassword {
+				authProvider = remaining[0].Provider
+			}
+			if err := s.userRepo.SetAuthProvider(tx, userID, authProvider); err != nil {
+				return err
+			}
+		}
+
+		accounts = &models.LinkedAccounts{
+			HasPassword: hasPassword,
+			Identities:  remaining,
+		}
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return accounts, err
 }
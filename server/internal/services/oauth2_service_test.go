@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tenderness/internal/oauth"
+	"tenderness/internal/repository"
+)
+
+func TestOAuthSignUpRejectsDeletedAccountEmail(t *testing.T) {
+	db := openTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	// До выдачи токенов вход не доходит
+	service := NewOAuth2Service(repository.NewTxManager(db), userRepo, repository.NewUserIdentityRepository(db),
+		nil, nil, nil, oauth.NewRegistry(), "test-state-secret", time.Minute, OAuthLinkVerified, time.Minute)
+
+	user := createTestUser(t, db)
+	if err := userRepo.Delete(user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	identity := &oauth.Identity{Subject: "deleted-" + user.Email, Email: user.Email, EmailVerified: true}
+	if _, err := service.signUp("google", identity); !errors.Is(err, ErrAccountDeactivated) {
+		t.Fatalf("signUp with the email of a deleted account: err = %v, want ErrAccountDeactivated", err)
+	}
+	if _, err := userRepo.GetByOAuthID("google", identity.Subject); err == nil {
+		t.Fatal("provider account was linked to a deleted account")
+	}
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrCartEmpty              = errors.New("cart is empty")
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrInvalidOrderTransition = errors.New("invalid order status transition")
+	ErrInsufficientStock      = errors.New("insufficient stock")
+)
+
+// expiredReservationsBatch ограничивает число заказов, отменяемых за один проход
+const expiredReservationsBatch = 100
+
+type OrderService struct {
+	tx             *repository.TxManager
+	orderRepo      *repository.OrderRepository
+	cartRepo       *repository.CartRepository
+	productRepo    *repository.ProductRepository
+	inventoryRepo  *repository.InventoryRepository
+	validator      *validator.Validate
+	reservationTTL time.Duration
+}
+
+func NewOrderService(tx *repository.TxManager, orderRepo *repository.OrderRepository, cartRepo *repository.CartRepository, productRepo *repository.ProductRepository, inventoryRepo *repository.InventoryRepository, reservationTTL time.Duration) *OrderService {
+	return &OrderService{
+		tx:             tx,
+		orderRepo:      orderRepo,
+		cartRepo:       cartRepo,
+		productRepo:    productRepo,
+		inventoryRepo:  inventoryRepo,
+		validator:      newValidator(),
+		reservationTTL: reservationTTL,
+	}
+}
+
+// PlaceOrder оформляет заказ из корзины пользователя в одной транзакции:
+// блокирует позиции корзины и товары, фиксирует название и цену товара,
+// создаёт заказ со статусом pending, резервирует товар на складе и очищает корзину.
+func (s *OrderService) PlaceOrder(userID int, req *models.PlaceOrderRequest) (*models.Order, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.cartRepo.GetOrCreateByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		UserID:          userID,
+		Status:          models.OrderStatusPending,
+		ShippingAddress: req.ShippingAddress,
+		Comment:         req.Comment,
+	}
+
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		cartItems, err := s.cartRepo.GetItemsForUpdate(tx, cart.ID)
+		if err != nil {
+			return err
+		}
+		if len(cartItems) == 0 {
+			return ErrCartEmpty
+		}
+
+		// Позиции отсортированы по product_id и variant_id, поэтому строки блокируются в одном порядке
+		for _, cartItem := range cartItems {
+			item, err := s.snapshotItem(tx, &cartItem)
+			if err != nil {
+				return err
+			}
+			order.Items = append(order.Items, *item)
+			order.Total += item.LineTotal
+		}
+		order.Total = roundMoney(order.Total)
+
+		if err := s.orderRepo.Create(tx, order); err != nil {
+			return err
+		}
+
+		expiresAt := time.Now().Add(s.reservationTTL)
+		for i := range order.Items {
+			item := &order.Items[i]
+			item.OrderID = order.ID
+			if err := s.orderRepo.AddItem(tx, item); err != nil {
+				return err
+			}
+
+			err := s.inventoryRepo.Reserve(tx, &models.StockReservation{
+				OrderID:   order.ID,
+				ProductID: *item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+				Status:    models.ReservationActive,
+				ExpiresAt: expiresAt,
+			})
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("%w: %s", ErrInsufficientStock, item.ProductName)
+				}
+				return err
+			}
+		}
+
+		change := &models.OrderStatusChange{
+			OrderID:   order.ID,
+			ToStatus:  models.OrderStatusPending,
+			ChangedBy: &userID,
+			Note:      "order placed",
+		}
+		if err := s.orderRepo.AddStatusChange(tx, change); err != nil {
+			return err
+		}
+		order.History = []models.OrderStatusChange{*change}
+
+		return s.cartRepo.ClearTx(tx, cart.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// snapshotItem блокирует товар (и вариант) позиции корзины, проверяет свободный остаток
+// и фиксирует название, артикул и цену на момент покупки
+func (s *OrderService) snapshotItem(tx *sqlx.Tx, cartItem *models.CartItem) (*models.OrderItem, error) {
+	product, err := s.productRepo.GetByIDForUpdate(tx, cartItem.ProductID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	productID := product.ID
+	item := &models.OrderItem{
+		ProductID:   &productID,
+		ProductName: product.Name,
+		UnitPrice:   product.Price,
+		Quantity:    cartItem.Quantity,
+	}
+	available := product.AvailableQuantity()
+
+	if cartItem.VariantID == nil {
+		hasVariants, err := s.productRepo.HasVariants(tx, product.ID)
+		if err != nil {
+			return nil, err
+		}
+		if hasVariants {
+			return nil, fmt.Errorf("%w: %s", ErrVariantRequired, product.Name)
+		}
+	} else {
+		variant, err := s.productRepo.GetVariantForUpdate(tx, product.ID, *cartItem.VariantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrVariantNotFound
+			}
+			return nil, err
+		}
+
+		variantID := variant.ID
+		item.VariantID = &variantID
+		item.SKU = variant.SKU
+		item.VariantName = variant.Label()
+		item.UnitPrice = variant.EffectivePrice(product)
+		available = variant.AvailableQuantity()
+	}
+
+	if available < cartItem.Quantity {
+		return nil, fmt.Errorf("%w: %s", ErrInsufficientStock, product.Name)
+	}
+
+	item.LineTotal = roundMoney(item.UnitPrice * float64(item.Quantity))
+	return item, nil
+}
+
+func (s *OrderService) GetOrders(userID, page, limit int) ([]models.Order, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 12
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset := (page - 1) * limit
+
+	orders, err := s.orderRepo.GetByUserID(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.orderRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// GetOrder возвращает заказ пользователя вместе с позициями и историей статусов
+func (s *OrderService) GetOrder(userID, orderID int) (*models.Order, error) {
+	order, err := s.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+
+	return order, nil
+}
+
+// GetOrderByID возвращает любой заказ с позициями и историей, для сотрудников
+func (s *OrderService) GetOrderByID(orderID int) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	if order.Items, err = s.orderRepo.GetItems(order.ID); err != nil {
+		return nil, err
+	}
+	if order.History, err = s.orderRepo.GetHistory(order.ID); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// CancelOrder отменяет заказ пользователя, пока он не оплачен
+func (s *OrderService) CancelOrder(userID, orderID int) (*models.Order, error) {
+	if _, err := s.GetOrder(userID, orderID); err != nil {
+		return nil, err
+	}
+
+	if err := s.ChangeStatus(orderID, models.OrderStatusCancelled, &userID, "cancelled by customer"); err != nil {
+		return nil, err
+	}
+
+	return s.GetOrder(userID, orderID)
+}
+
+// UpdateStatus меняет статус заказа по запросу сотрудника
+func (s *OrderService) UpdateStatus(changedBy, orderID int, req *models.UpdateOrderStatusRequest) (*models.Order, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.ChangeStatus(orderID, req.Status, &changedBy, req.Note); err != nil {
+		return nil, err
+	}
+
+	return s.GetOrderByID(orderID)
+}
+
+// ChangeStatus переводит заказ в новый статус, проверяя допустимость перехода,
+// и записывает изменение в историю. changedBy равен nil для системных изменений.
+func (s *OrderService) ChangeStatus(orderID int, status models.OrderStatus, changedBy *int, note string) error {
+	return s.tx.WithTx(func(tx *sqlx.Tx) error {
+		return s.ChangeStatusTx(tx, orderID, status, changedBy, note)
+	})
+}
+
+// ChangeStatusTx делает то же, что ChangeStatus, внутри уже открытой транзакции
+func (s *OrderService) ChangeStatusTx(tx *sqlx.Tx, orderID int, status models.OrderStatus, changedBy *int, note string) error {
+	if !status.IsValid() {
+		return ErrInvalidOrderTransition
+	}
+
+	order, err := s.orderRepo.GetByIDForUpdate(tx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOrderNotFound
+		}
+		return err
+	}
+
+	if !order.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidOrderTransition, order.Status, status)
+	}
+
+	if err := s.applyInventory(tx, order, status); err != nil {
+		return err
+	}
+
+	if err := s.orderRepo.UpdateStatus(tx, order.ID, status); err != nil {
+		return err
+	}
+
+	return s.orderRepo.AddStatusChange(tx, &models.OrderStatusChange{
+		OrderID:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   status,
+		ChangedBy:  changedBy,
+		Note:       note,
+	})
+}
+
+// applyInventory изменяет складские остатки при смене статуса заказа:
+//   - оплата списывает зарезервированный товар;
+//   - отмена неоплаченного заказа снимает резерв;
+//   - возврат до отправки (paid, packed) возвращает товар на склад.
+//
+// Возврат после доставки остатки не меняет: товар находится у покупателя.
+func (s *OrderService) applyInventory(tx *sqlx.Tx, order *models.Order, status models.OrderStatus) error {
+	var (
+		from  models.ReservationStatus
+		apply func(*sqlx.Tx, *models.StockReservation) error
+	)
+
+	switch {
+	case status == models.OrderStatusPaid:
+		from, apply = models.ReservationActive, s.inventoryRepo.Commit
+	case status == models.OrderStatusCancelled:
+		from, apply = models.ReservationActive, s.inventoryRepo.Release
+	case status == models.OrderStatusRefunded && order.Status != models.OrderStatusDelivered:
+		from, apply = models.ReservationCommitted, s.inventoryRepo.Restock
+	default:
+		return nil
+	}
+
+	reservations, err := s.inventoryRepo.GetByOrderForUpdate(tx, order.ID, from)
+	if err != nil {
+		return err
+	}
+
+	for i := range reservations {
+		if err := apply(tx, &reservations[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpireReservations отменяет неоплаченные заказы с истёкшим резервом и возвращает число отменённых заказов.
+// Если по заказу идёт оплата, резерв держится ещё reservationTTL с момента создания платежа;
+// оплата, прошедшая после отмены, возвращается автоматически.
+func (s *OrderService) ExpireReservations() (int, error) {
+	now := time.Now()
+	orderIDs, err := s.inventoryRepo.GetOrdersWithExpiredReservations(now, now.Add(-s.reservationTTL), expiredReservationsBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, orderID := range orderIDs {
+		err := s.ChangeStatus(orderID, models.OrderStatusCancelled, nil, "stock reservation expired")
+		if err != nil {
+			// Заказ мог быть оплачен или отменён параллельно
+			if !errors.Is(err, ErrInvalidOrderTransition) {
+				log.Printf("Failed to cancel order %d with expired reservation: %v", orderID, err)
+			}
+			continue
+		}
+		cancelled++
+	}
+
+	return cancelled, nil
+}
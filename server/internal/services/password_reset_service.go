@@ -0,0 +1,131 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/mailer"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+type PasswordResetService struct {
+	tx        *repository.TxManager
+	resetRepo *repository.PasswordResetRepository
+	userRepo  *repository.UserRepository
+	tokens    *TokenService
+	mailer    mailer.Mailer
+	baseURL   string
+	ttl       time.Duration
+	validator *validator.Validate
+}
+
+func NewPasswordResetService(tx *repository.TxManager, resetRepo *repository.PasswordResetRepository, userRepo *repository.UserRepository, tokens *TokenService, m mailer.Mailer, baseURL string, ttl time.Duration) *PasswordResetService {
+	return &PasswordResetService{
+		tx:        tx,
+		resetRepo: resetRepo,
+		userRepo:  userRepo,
+		tokens:    tokens,
+		mailer:    m,
+		baseURL:   baseURL,
+		ttl:       ttl,
+		validator: newValidator(),
+	}
+}
+
+// Forgot отправляет ссылку для сброса пароля. Для неизвестного email ошибка
+// не возвращается, чтобы по ответу нельзя было узнать, есть ли такой аккаунт.
+func (s *PasswordResetService) Forgot(req *models.ForgotPasswordRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rawToken, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	token := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		return s.resetRepo.Create(tx, token)
+	}); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s/reset-password?token=%s\n\n"+
+			"Ссылка действует %s. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			s.baseURL, url.QueryEscape(rawToken), s.ttl),
+	}
+
+	// Отправка не блокирует ответ: время ответа не должно зависеть от того, существует ли аккаунт
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// Reset задаёт новый пароль по токену из письма и завершает все входы пользователя
+func (s *PasswordResetService) Reset(req *models.ResetPasswordRequest) error {
+	if err := s.validator.Struct(req); err != nil {
+		return err
+	}
+
+	var userID int
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		token, err := s.resetRepo.GetByHashForUpdate(tx, hashToken(req.Token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		if err := s.userRepo.UpdatePasswordTx(tx, token.UserID, req.Password); err != nil {
+			return err
+		}
+
+		userID = token.UserID
+		return s.resetRepo.MarkUsed(tx, token.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.tokens.RevokeAll(userID)
+}
+
+// DeleteExpired удаляет истёкшие токены сброса пароля
+func (s *PasswordResetService) DeleteExpired() error {
+	_, err := s.resetRepo.DeleteExpired()
+	return err
+}
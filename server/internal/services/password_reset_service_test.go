@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type passwordResetFixture struct {
+	service   *PasswordResetService
+	tx        *repository.TxManager
+	resetRepo *repository.PasswordResetRepository
+	userRepo  *repository.UserRepository
+	user      *models.User
+}
+
+func newPasswordResetFixture(t *testing.T) *passwordResetFixture {
+	t.Helper()
+
+	db := openTestDB(t)
+	txManager := repository.NewTxManager(db)
+	resetRepo := repository.NewPasswordResetRepository(db)
+	userRepo := repository.NewUserRepository(db)
+
+	return &passwordResetFixture{
+		// Отклонённый токен не доходит до отзыва входов и отправки писем
+		service:   NewPasswordResetService(txManager, resetRepo, userRepo, nil, nil, "http://localhost", time.Hour),
+		tx:        txManager,
+		resetRepo: resetRepo,
+		userRepo:  userRepo,
+		user:      createTestUser(t, db),
+	}
+}
+
+// issueToken сохраняет токен сброса пароля так же, как Forgot, и возвращает его открытое значение
+func (f *passwordResetFixture) issueToken(t *testing.T, expiresAt time.Time) (string, *models.PasswordResetToken) {
+	t.Helper()
+
+	rawToken, err := randomToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &models.PasswordResetToken{
+		UserID:    f.user.ID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: expiresAt,
+	}
+	if err := f.tx.WithTx(func(tx *sqlx.Tx) error {
+		return f.resetRepo.Create(tx, token)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return rawToken, token
+}
+
+// assertPasswordUnchanged проверяет, что пароль пользователя остался прежним
+func (f *passwordResetFixture) assertPasswordUnchanged(t *testing.T) {
+	t.Helper()
+
+	user, err := f.userRepo.GetByID(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.userRepo.ValidatePassword("password123", user.Password); err != nil {
+		t.Fatal("password was changed by a rejected reset token")
+	}
+}
+
+func TestPasswordResetRejectsExpiredToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	rawToken, _ := f.issueToken(t, time.Now().Add(-time.Minute))
+
+	err := f.service.Reset(&models.ResetPasswordRequest{Token: rawToken, Password: "new-password"})
+	if !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("Reset with expired token: err = %v, want ErrInvalidResetToken", err)
+	}
+	f.assertPasswordUnchanged(t)
+}
+
+func TestPasswordResetRejectsUsedToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	rawToken, token := f.issueToken(t, time.Now().Add(time.Hour))
+	if err := f.tx.WithTx(func(tx *sqlx.Tx) error {
+		return f.resetRepo.MarkUsed(tx, token.ID)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := f.service.Reset(&models.ResetPasswordRequest{Token: rawToken, Password: "new-password"})
+	if !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("Reset with used token: err = %v, want ErrInvalidResetToken", err)
+	}
+	f.assertPasswordUnchanged(t)
+}
+
+func TestPasswordResetRejectsSupersededToken(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	// Новое письмо отменяет ссылку из предыдущего
+	rawToken, _ := f.issueToken(t, time.Now().Add(time.Hour))
+	f.issueToken(t, time.Now().Add(time.Hour))
+
+	err := f.service.Reset(&models.ResetPasswordRequest{Token: rawToken, Password: "new-password"})
+	if !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("Reset with superseded token: err = %v, want ErrInvalidResetToken", err)
+	}
+	f.assertPasswordUnchanged(t)
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/payments"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrUnknownPaymentProvider = errors.New("unknown payment provider")
+	ErrPaymentsNotConfigured  = errors.New("payments are not configured")
+	ErrPaymentNotFound        = errors.New("payment not found")
+	ErrPaymentNotRefundable   = errors.New("payment cannot be refunded in its current status")
+	ErrOrderNotPayable        = errors.New("order cannot be paid in its current status")
+	ErrPaymentAmountMismatch  = errors.New("paid amount does not match the payment")
+)
+
+// refundRetryBatch ограничивает число возвратов, повторяемых за один проход
+const refundRetryBatch = 50
+
+type PaymentService struct {
+	tx              *repository.TxManager
+	paymentRepo     *repository.PaymentRepository
+	orderService    *OrderService
+	providers       map[string]payments.PaymentProvider
+	defaultProvider string
+	currency        string
+	validator       *validator.Validate
+}
+
+func NewPaymentService(tx *repository.TxManager, paymentRepo *repository.PaymentRepository, orderService *OrderService, defaultProvider, currency string, providers ...payments.PaymentProvider) *PaymentService {
+	registry := make(map[string]payments.PaymentProvider, len(providers))
+	for _, provider := range providers {
+		registry[provider.Name()] = provider
+	}
+
+	return &PaymentService{
+		tx:              tx,
+		paymentRepo:     paymentRepo,
+		orderService:    orderService,
+		providers:       registry,
+		defaultProvider: defaultProvider,
+		currency:        currency,
+		validator:       newValidator(),
+	}
+}
+
+// StartPayment создаёт платёж у провайдера по умолчанию для неоплаченного заказа пользователя
+func (s *PaymentService) StartPayment(userID, orderID int) (*models.PaymentResponse, error) {
+	order, err := s.orderService.GetOrder(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, ErrOrderNotPayable
+	}
+	if s.defaultProvider == "" {
+		return nil, ErrPaymentsNotConfigured
+	}
+
+	provider, err := s.provider(s.defaultProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := provider.CreateIntent(context.Background(), payments.IntentRequest{
+		OrderID:  order.ID,
+		Amount:   toMinorUnits(order.Total),
+		Currency: s.currency,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	payment := &models.Payment{
+		OrderID:           order.ID,
+		Provider:          provider.Name(),
+		ProviderPaymentID: intent.ID,
+		Status:            models.PaymentStatusPending,
+		Amount:            intent.Amount,
+		Currency:          intent.Currency,
+	}
+	if err := s.paymentRepo.Create(payment); err != nil {
+		return nil, err
+	}
+
+	return &models.PaymentResponse{
+		Payment:      *payment,
+		ClientSecret: intent.ClientSecret,
+	}, nil
+}
+
+// ConfirmPayment подтверждает и списывает последний платёж по заказу.
+// При успешном списании заказ переходит в статус paid. Если заказ отменили, пока шла
+// оплата, деньги возвращаются автоматически и возвращается ErrOrderNotPayable.
+func (s *PaymentService) ConfirmPayment(userID, orderID int, req *models.ConfirmPaymentRequest) (*models.Payment, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderService.GetOrder(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := s.paymentRepo.GetLatestByOrderID(orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+	if payment.Status != models.PaymentStatusPending {
+		return payment, nil
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, ErrOrderNotPayable
+	}
+
+	provider, err := s.provider(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	intent, err := provider.Confirm(ctx, payment.ProviderPaymentID, req.PaymentMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm payment: %w", err)
+	}
+	if intent.Status == payments.IntentRequiresCapture {
+		if intent, err = provider.Capture(ctx, payment.ProviderPaymentID); err != nil {
+			return nil, fmt.Errorf("failed to capture payment: %w", err)
+		}
+	}
+
+	status, failureReason := paymentStatusFromIntent(intent.Status), intent.FailureReason
+	if status == models.PaymentStatusSucceeded && intent.Amount != payment.Amount {
+		log.Printf("Payment %d was paid with %d instead of %d, not marking order %d paid", payment.ID, intent.Amount, payment.Amount, payment.OrderID)
+		status, failureReason = models.PaymentStatusFailed, ErrPaymentAmountMismatch.Error()
+	}
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		locked, err := s.paymentRepo.GetByProviderIDForUpdate(tx, payment.Provider, payment.ProviderPaymentID)
+		if err != nil {
+			return err
+		}
+		payment = locked
+		return s.settle(tx, payment, status, failureReason, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch payment.Status {
+	case models.PaymentStatusRefundPending:
+		if refunded, err := s.refund(payment, nil); err != nil {
+			log.Printf("Failed to refund payment %d, will retry: %v", payment.ID, err)
+		} else {
+			payment = refunded
+		}
+		return payment, ErrOrderNotPayable
+	case models.PaymentStatusFailed:
+		return payment, payments.ErrPaymentDeclined
+	}
+	return payment, nil
+}
+
+// RefundOrder возвращает деньги по оплаченному заказу и переводит заказ в статус refunded.
+// Перед обращением к провайдеру платёж помечается refund_pending, поэтому возврат,
+// результат которого не удалось сохранить, можно безопасно повторить.
+func (s *PaymentService) RefundOrder(orderID int, changedBy *int) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetLatestByOrderID(orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		locked, err := s.paymentRepo.GetByProviderIDForUpdate(tx, payment.Provider, payment.ProviderPaymentID)
+		if err != nil {
+			return err
+		}
+		payment = locked
+
+		switch payment.Status {
+		case models.PaymentStatusSucceeded:
+			return s.setStatus(tx, payment, models.PaymentStatusRefundPending, "")
+		case models.PaymentStatusRefundPending:
+			// Предыдущая попытка не завершилась - повторяем её
+			return nil
+		default:
+			return ErrPaymentNotRefundable
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refund(payment, changedBy)
+}
+
+// RetryRefunds повторяет незавершённые возвраты и возвращает число завершённых
+func (s *PaymentService) RetryRefunds() (int, error) {
+	pending, err := s.paymentRepo.GetByStatus(models.PaymentStatusRefundPending, refundRetryBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	refunded := 0
+	for i := range pending {
+		if _, err := s.refund(&pending[i], nil); err != nil {
+			log.Printf("Failed to refund payment %d: %v", pending[i].ID, err)
+			continue
+		}
+		refunded++
+	}
+
+	return refunded, nil
+}
+
+// refund отправляет провайдеру возврат платежа в статусе refund_pending и сохраняет результат.
+// Ключ идемпотентности привязан к платежу, поэтому повторная попытка не вернёт деньги дважды.
+func (s *PaymentService) refund(payment *models.Payment, changedBy *int) (*models.Payment, error) {
+	provider, err := s.provider(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyKey := fmt.Sprintf("refund-%d", payment.ID)
+	if _, err := provider.Refund(context.Background(), payment.ProviderPaymentID, payment.Amount, idempotencyKey); err != nil {
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		locked, err := s.paymentRepo.GetByProviderIDForUpdate(tx, payment.Provider, payment.ProviderPaymentID)
+		if err != nil {
+			return err
+		}
+		payment = locked
+		return s.settle(tx, payment, models.PaymentStatusRefunded, payment.FailureReason, changedBy)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// HandleWebhook проверяет подпись вебхука и применяет событие.
+// Повторная доставка уже обработанного события ничего не меняет и возвращает duplicate=true.
+func (s *PaymentService) HandleWebhook(providerName string, payload []byte, header func(string) string) (duplicate bool, err error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return false, err
+	}
+
+	event, err := provider.ParseWebhook(payload, header(provider.SignatureHeader()))
+	if err != nil {
+		return false, err
+	}
+
+	var settled *models.Payment
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		recorded, err := s.paymentRepo.RecordWebhookEvent(tx, provider.Name(), event.ID, string(event.Type))
+		if err != nil {
+			return err
+		}
+		if !recorded {
+			duplicate = true
+			return nil
+		}
+
+		payment, err := s.paymentRepo.GetByProviderIDForUpdate(tx, provider.Name(), event.IntentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrPaymentNotFound
+			}
+			return err
+		}
+
+		settled = payment
+
+		switch event.Type {
+		case payments.EventPaymentSucceeded:
+			// Оплата не той суммы не оплачивает заказ: платёж отмечается неудачным,
+			// а событие - обработанным, чтобы провайдер не присылал его снова
+			if event.Amount != payment.Amount {
+				log.Printf("Payment %d was paid with %d instead of %d, not marking order %d paid", payment.ID, event.Amount, payment.Amount, payment.OrderID)
+				return s.settle(tx, payment, models.PaymentStatusFailed, ErrPaymentAmountMismatch.Error(), nil)
+			}
+			return s.settle(tx, payment, models.PaymentStatusSucceeded, "", nil)
+		case payments.EventPaymentFailed:
+			return s.settle(tx, payment, models.PaymentStatusFailed, payments.ErrPaymentDeclined.Error(), nil)
+		case payments.EventPaymentRefunded:
+			return s.settle(tx, payment, models.PaymentStatusRefunded, payment.FailureReason, nil)
+		default:
+			log.Printf("Ignoring %s payment webhook event %s of type %s", provider.Name(), event.ID, event.Type)
+			return nil
+		}
+	})
+	if err != nil {
+		return duplicate, err
+	}
+
+	// Оплата пришла по заказу, который уже нельзя оплатить. Неудачный возврат
+	// повторит RetryRefunds, поэтому вебхук всё равно считается обработанным.
+	if settled != nil && settled.Status == models.PaymentStatusRefundPending {
+		if _, err := s.refund(settled, nil); err != nil {
+			log.Printf("Failed to refund payment %d, will retry: %v", settled.ID, err)
+		}
+	}
+
+	return duplicate, nil
+}
+
+// settle сохраняет новый статус платежа и переводит заказ в соответствующий статус.
+// Повторное применение того же статуса ничего не меняет. Если оплата прошла по заказу,
+// который уже нельзя оплатить (например, отменён по истечении резерва), платёж
+// переводится в refund_pending, а деньги возвращает вызывающий код через refund.
+func (s *PaymentService) settle(tx *sqlx.Tx, payment *models.Payment, status models.PaymentStatus, failureReason string, changedBy *int) error {
+	if payment.Status == status {
+		return nil
+	}
+
+	switch status {
+	case models.PaymentStatusSucceeded, models.PaymentStatusFailed:
+		// Исход оплаты применяется только к ожидающему платежу: запоздавшее событие его не меняет
+		if payment.Status != models.PaymentStatusPending {
+			return nil
+		}
+	case models.PaymentStatusRefunded:
+		if payment.Status != models.PaymentStatusSucceeded && payment.Status != models.PaymentStatusRefundPending {
+			return nil
+		}
+	}
+
+	if status == models.PaymentStatusSucceeded {
+		err := s.orderService.ChangeStatusTx(tx, payment.OrderID, models.OrderStatusPaid, nil, "payment succeeded")
+		if errors.Is(err, ErrInvalidOrderTransition) {
+			log.Printf("Order %d cannot be paid anymore, refunding payment %d: %v", payment.OrderID, payment.ID, err)
+			return s.setStatus(tx, payment, models.PaymentStatusRefundPending, ErrOrderNotPayable.Error())
+		}
+		if err != nil {
+			return err
+		}
+		return s.setStatus(tx, payment, status, "")
+	}
+
+	if err := s.setStatus(tx, payment, status, failureReason); err != nil {
+		return err
+	}
+	if status != models.PaymentStatusRefunded {
+		return nil
+	}
+
+	err := s.orderService.ChangeStatusTx(tx, payment.OrderID, models.OrderStatusRefunded, changedBy, "payment refunded")
+	if errors.Is(err, ErrInvalidOrderTransition) {
+		// Возврат по неоплаченному заказу: заказ остаётся в прежнем статусе
+		log.Printf("Order %d was not moved to refunded after payment %d: %v", payment.OrderID, payment.ID, err)
+		return nil
+	}
+	return err
+}
+
+func (s *PaymentService) setStatus(tx *sqlx.Tx, payment *models.Payment, status models.PaymentStatus, failureReason string) error {
+	if err := s.paymentRepo.UpdateStatus(tx, payment.ID, status, failureReason); err != nil {
+		return err
+	}
+	payment.Status = status
+	payment.FailureReason = failureReason
+	return nil
+}
+
+func (s *PaymentService) provider(name string) (payments.PaymentProvider, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, name)
+	}
+	return provider, nil
+}
+
+func paymentStatusFromIntent(status payments.IntentStatus) models.PaymentStatus {
+	switch status {
+	case payments.IntentSucceeded:
+		return models.PaymentStatusSucceeded
+	case payments.IntentFailed:
+		return models.PaymentStatusFailed
+	case payments.IntentRefunded:
+		return models.PaymentStatusRefunded
+	default:
+		return models.PaymentStatusPending
+	}
+}
+
+// toMinorUnits переводит сумму в рублях в копейки
+func toMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
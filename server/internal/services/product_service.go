@@ -1,19 +1,40 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
+
 	"tenderness/internal/domain/models"
 	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrCategoryExists     = errors.New("category with this name already exists")
+	ErrCategoryInUse      = errors.New("category still has products")
+	ErrStockBelowReserved = errors.New("stock quantity cannot be lower than reserved quantity")
+	ErrVariantExists      = errors.New("variant with this SKU or options already exists")
+	ErrVariantInUse       = errors.New("variant has orders and cannot be deleted")
+	ErrProductReserved    = errors.New("product has reserved stock, variants cannot be added")
 )
 
 type ProductService struct {
+	tx           *repository.TxManager
 	productRepo  *repository.ProductRepository
 	categoryRepo *repository.CategoryRepository
+	validator    *validator.Validate
 }
 
-func NewProductService(productRepo *repository.ProductRepository, categoryRepo *repository.CategoryRepository) *ProductService {
+func NewProductService(tx *repository.TxManager, productRepo *repository.ProductRepository, categoryRepo *repository.CategoryRepository) *ProductService {
 	return &ProductService{
+		tx:           tx,
 		productRepo:  productRepo,
 		categoryRepo: categoryRepo,
+		validator:    newValidator(),
 	}
 }
 
@@ -120,3 +141,346 @@ func (s *ProductService) SearchProducts(query string, page, limit int) ([]models
 func (s *ProductService) GetCategories() ([]models.Category, error) {
 	return s.categoryRepo.GetAll()
 }
+
+// Админские операции с каталогом
+
+// GetAllProducts возвращает товары вместе со скрытыми
+func (s *ProductService) GetAllProducts(page, limit int) ([]models.Product, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset := (page - 1) * limit
+
+	products, err := s.productRepo.GetAllWithDeleted(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.productRepo.CountWithDeleted()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+func (s *ProductService) CreateProduct(req *models.ProductRequest) (*models.Product, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	if err := s.checkCategory(req.Category); err != nil {
+		return nil, err
+	}
+
+	product := &models.Product{
+		Name:          req.Name,
+		Description:   req.Description,
+		Price:         req.Price,
+		ImageURL:      req.ImageURL,
+		Category:      req.Category,
+		StockQuantity: req.StockQuantity,
+	}
+
+	if err := s.productRepo.Create(product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (s *ProductService) UpdateProduct(id int, req *models.ProductRequest) (*models.Product, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	var product *models.Product
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		var err error
+		product, err = s.productRepo.GetByIDWithDeletedForUpdate(tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		// Скрытый товар можно оставить в удалённой категории, новую категорию проверяем
+		if req.Category != product.Category {
+			if err := s.checkCategory(req.Category); err != nil {
+				return err
+			}
+		}
+
+		hasVariants, err := s.productRepo.HasVariants(tx, id)
+		if err != nil {
+			return err
+		}
+		if !hasVariants && req.StockQuantity < product.ReservedQuantity {
+			return ErrStockBelowReserved
+		}
+
+		product.Name = req.Name
+		product.Description = req.Description
+		product.Price = req.Price
+		product.ImageURL = req.ImageURL
+		product.Category = req.Category
+		product.StockQuantity = req.StockQuantity
+
+		return s.productRepo.Update(tx, product)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (s *ProductService) DeleteProduct(id int) error {
+	err := s.productRepo.SoftDelete(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+func (s *ProductService) RestoreProduct(id int) error {
+	err := s.productRepo.Restore(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// GetVariants возвращает варианты товара, в том числе скрытого
+func (s *ProductService) GetVariants(productID int) ([]models.ProductVariant, error) {
+	if _, err := s.productRepo.GetByIDWithDeleted(productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	return s.productRepo.GetVariants(productID)
+}
+
+// CreateVariant добавляет вариант товару. Остаток товара после этого считается по вариантам,
+// поэтому вариант нельзя добавить, пока товар без вариантов зарезервирован заказами.
+func (s *ProductService) CreateVariant(productID int, req *models.ProductVariantRequest) (*models.ProductVariant, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	variant := &models.ProductVariant{ProductID: productID}
+	applyVariantRequest(variant, req)
+
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		// Товар блокируется первым, как при резервировании, триггер всё равно изменит его строку
+		product, err := s.productRepo.GetByIDWithDeletedForUpdate(tx, productID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		hasVariants, err := s.productRepo.HasVariants(tx, productID)
+		if err != nil {
+			return err
+		}
+		if !hasVariants && product.ReservedQuantity > 0 {
+			return ErrProductReserved
+		}
+
+		return s.productRepo.CreateVariant(tx, variant)
+	})
+	if err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, ErrVariantExists
+		}
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (s *ProductService) UpdateVariant(productID, variantID int, req *models.ProductVariantRequest) (*models.ProductVariant, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	var variant *models.ProductVariant
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		var err error
+		if variant, err = s.lockVariant(tx, productID, variantID); err != nil {
+			return err
+		}
+		if req.StockQuantity < variant.ReservedQuantity {
+			return ErrStockBelowReserved
+		}
+
+		applyVariantRequest(variant, req)
+		return s.productRepo.UpdateVariant(tx, variant)
+	})
+	if err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, ErrVariantExists
+		}
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+// DeleteVariant удаляет вариант. Вариант, на который уже резервировали заказы, удалить нельзя:
+// резервы хранят ссылку на него.
+func (s *ProductService) DeleteVariant(productID, variantID int) error {
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		if _, err := s.lockVariant(tx, productID, variantID); err != nil {
+			return err
+		}
+		return s.productRepo.DeleteVariant(tx, variantID)
+	})
+	if repository.IsForeignKeyViolation(err) {
+		return ErrVariantInUse
+	}
+	return err
+}
+
+func (s *ProductService) lockVariant(tx *sqlx.Tx, productID, variantID int) (*models.ProductVariant, error) {
+	if _, err := s.productRepo.GetByIDWithDeletedForUpdate(tx, productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	variant, err := s.productRepo.GetVariantForUpdate(tx, productID, variantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+	return variant, nil
+}
+
+func applyVariantRequest(variant *models.ProductVariant, req *models.ProductVariantRequest) {
+	variant.SKU = req.SKU
+	variant.Size = req.Size
+	variant.Color = req.Color
+	variant.Material = req.Material
+	variant.Price = req.Price
+	variant.StockQuantity = req.StockQuantity
+	variant.ImageURLs = pq.StringArray{}
+	if req.ImageURLs != nil {
+		variant.ImageURLs = req.ImageURLs
+	}
+}
+
+// GetAllCategories возвращает категории вместе со скрытыми
+func (s *ProductService) GetAllCategories() ([]models.Category, error) {
+	return s.categoryRepo.GetAllWithDeleted()
+}
+
+func (s *ProductService) CreateCategory(req *models.CategoryRequest) (*models.Category, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	category := &models.Category{
+		Name:        req.Name,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+	}
+
+	if err := s.categoryRepo.Create(category); err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *ProductService) UpdateCategory(id int, req *models.CategoryRequest) (*models.Category, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	var category *models.Category
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		var err error
+		category, err = s.categoryRepo.GetByIDForUpdate(tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrCategoryNotFound
+			}
+			return err
+		}
+
+		previousName := category.Name
+		category.Name = req.Name
+		category.Description = req.Description
+		category.ImageURL = req.ImageURL
+
+		return s.categoryRepo.Update(tx, category, previousName)
+	})
+	if err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// DeleteCategory скрывает категорию, если в ней не осталось видимых товаров
+func (s *ProductService) DeleteCategory(id int) error {
+	category, err := s.categoryRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCategoryNotFound
+		}
+		return err
+	}
+
+	count, err := s.productRepo.CountByCategory(category.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCategoryInUse
+	}
+
+	err = s.categoryRepo.SoftDelete(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+func (s *ProductService) RestoreCategory(id int) error {
+	err := s.categoryRepo.Restore(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+func (s *ProductService) checkCategory(name string) error {
+	if _, err := s.categoryRepo.GetByName(name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCategoryNotFound
+		}
+		return err
+	}
+	return nil
+}
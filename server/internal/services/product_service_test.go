@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+)
+
+func TestAdminVariants(t *testing.T) {
+	f := newCheckoutFixture(t)
+	productRepo := repository.NewProductRepository(f.db)
+	service := NewProductService(repository.NewTxManager(f.db), productRepo, repository.NewCategoryRepository(f.db))
+	sku := "TEST-" + time.Now().Format("150405.000000")
+
+	// Резерв товара без вариантов потерялся бы, когда остаток начнут считать по вариантам
+	f.placeOrder(t)
+	if _, err := service.CreateVariant(f.product.ID, &models.ProductVariantRequest{SKU: sku + "-R"}); !errors.Is(err, ErrProductReserved) {
+		t.Fatalf("CreateVariant on a reserved product: err = %v, want ErrProductReserved", err)
+	}
+
+	product := &models.Product{Name: "Test product with variants", Price: 900, Category: "Test"}
+	if err := productRepo.Create(product); err != nil {
+		t.Fatal(err)
+	}
+	price := 1200.0
+	variant, err := service.CreateVariant(product.ID, &models.ProductVariantRequest{
+		SKU: sku, Size: "M", Price: &price, StockQuantity: 3,
+	})
+	if err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+	if _, err := service.CreateVariant(product.ID, &models.ProductVariantRequest{SKU: sku, Size: "L"}); !errors.Is(err, ErrVariantExists) {
+		t.Fatalf("CreateVariant with a taken SKU: err = %v, want ErrVariantExists", err)
+	}
+
+	// Остаток товара пересчитывается по вариантам
+	var stock int
+	if err := f.db.Get(&stock, `SELECT stock_quantity FROM products WHERE id = $1`, product.ID); err != nil {
+		t.Fatal(err)
+	}
+	if stock != 3 {
+		t.Fatalf("product stock = %d, want 3 from its variant", stock)
+	}
+
+	cartRepo := repository.NewCartRepository(f.db)
+	cart, err := cartRepo.GetOrCreateByUserID(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cartRepo.AddItem(cart.ID, product.ID, &variant.ID, 2, price); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.orders.PlaceOrder(f.user.ID, &models.PlaceOrderRequest{ShippingAddress: "Москва, ул. Тестовая, д. 1"}); err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	update := &models.ProductVariantRequest{SKU: sku, Size: "M", StockQuantity: 1}
+	if _, err := service.UpdateVariant(product.ID, variant.ID, update); !errors.Is(err, ErrStockBelowReserved) {
+		t.Fatalf("UpdateVariant below reserved: err = %v, want ErrStockBelowReserved", err)
+	}
+	update.StockQuantity = 5
+	updated, err := service.UpdateVariant(product.ID, variant.ID, update)
+	if err != nil {
+		t.Fatalf("UpdateVariant: %v", err)
+	}
+	if updated.Price != nil || updated.StockQuantity != 5 {
+		t.Fatalf("updated variant = %+v, want product price and stock 5", updated)
+	}
+
+	if _, err := service.UpdateVariant(f.product.ID, variant.ID, update); !errors.Is(err, ErrVariantNotFound) {
+		t.Fatalf("UpdateVariant of another product's variant: err = %v, want ErrVariantNotFound", err)
+	}
+	if err := service.DeleteVariant(product.ID, variant.ID); !errors.Is(err, ErrVariantInUse) {
+		t.Fatalf("DeleteVariant with reservations: err = %v, want ErrVariantInUse", err)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrReviewExists     = errors.New("you have already reviewed this product")
+	ErrReviewNotAllowed = errors.New("only customers who received this product can review it")
+)
+
+type ReviewService struct {
+	tx              *repository.TxManager
+	reviewRepo      *repository.ReviewRepository
+	productRepo     *repository.ProductRepository
+	orderRepo       *repository.OrderRepository
+	validator       *validator.Validate
+	requirePurchase bool
+}
+
+// NewReviewService создаёт сервис отзывов. Если requirePurchase включён,
+// оставить отзыв можно только на товар из доставленного заказа.
+func NewReviewService(tx *repository.TxManager, reviewRepo *repository.ReviewRepository, productRepo *repository.ProductRepository, orderRepo *repository.OrderRepository, requirePurchase bool) *ReviewService {
+	return &ReviewService{
+		tx:              tx,
+		reviewRepo:      reviewRepo,
+		productRepo:     productRepo,
+		orderRepo:       orderRepo,
+		validator:       newValidator(),
+		requirePurchase: requirePurchase,
+	}
+}
+
+func (s *ReviewService) GetProductReviews(productID, page, limit int) ([]models.Review, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	offset := (page - 1) * limit
+
+	reviews, err := s.reviewRepo.GetByProductID(productID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.reviewRepo.CountByProductID(productID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return reviews, total, nil
+}
+
+func (s *ReviewService) CreateReview(userID int, req *models.CreateReviewRequest) (*models.Review, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	if s.requirePurchase {
+		delivered, err := s.orderRepo.HasDeliveredProduct(userID, req.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if !delivered {
+			return nil, ErrReviewNotAllowed
+		}
+	}
+
+	review := &models.Review{
+		ProductID: req.ProductID,
+		UserID:    userID,
+		Rating:    req.Rating,
+		Title:     req.Title,
+		Body:      req.Body,
+	}
+
+	err := s.withProductLock(req.ProductID, func(tx *sqlx.Tx) error {
+		exists, err := s.reviewRepo.ExistsForUser(tx, req.ProductID, userID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrReviewExists
+		}
+
+		return s.reviewRepo.Create(tx, review)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (s *ReviewService) UpdateReview(userID, reviewID int, req *models.ReviewRequest) (*models.Review, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	review, err := s.getOwnReview(userID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Rating = req.Rating
+	review.Title = req.Title
+	review.Body = req.Body
+
+	err = s.withProductLock(review.ProductID, func(tx *sqlx.Tx) error {
+		return s.reviewRepo.Update(tx, review)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (s *ReviewService) DeleteReview(userID, reviewID int) error {
+	review, err := s.getOwnReview(userID, reviewID)
+	if err != nil {
+		return err
+	}
+
+	return s.withProductLock(review.ProductID, func(tx *sqlx.Tx) error {
+		err := s.reviewRepo.Delete(tx, review.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrReviewNotFound
+		}
+		return err
+	})
+}
+
+func (s *ReviewService) getOwnReview(userID, reviewID int) (*models.Review, error) {
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	if review.UserID != userID {
+		return nil, ErrReviewNotFound
+	}
+	return review, nil
+}
+
+// withProductLock выполняет fn в транзакции под блокировкой строки товара и затем
+// пересчитывает рейтинг. Блокировка нужна, чтобы параллельные изменения отзывов
+// одного товара не перезаписали пересчёт друг друга.
+func (s *ReviewService) withProductLock(productID int, fn func(tx *sqlx.Tx) error) error {
+	return s.tx.WithTx(func(tx *sqlx.Tx) error {
+		if _, err := s.productRepo.GetByIDForUpdate(tx, productID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		return s.reviewRepo.RecalculateProductRating(tx, productID)
+	})
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"tenderness/internal/repository"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// revocationCacheTTL - как часто кэш отзывов перечитывается из БД. Отзывы,
+// сделанные этим процессом, применяются сразу, сделанные другими экземплярами -
+// с задержкой не больше этого интервала.
+const revocationCacheTTL = 15 * time.Second
+
+// RevocationService проверяет отзыв access токенов. Данные хранятся в Postgres,
+// проверка идёт по кэшу в памяти, поэтому не добавляет запрос к БД на каждый запрос.
+type RevocationService struct {
+	repo        *repository.TokenRevocationRepository
+	sessionRepo *repository.SessionRepository
+	accessTTL   time.Duration
+
+	mu              sync.RWMutex
+	revoked         map[string]time.Time
+	revokedSessions map[string]time.Time
+	validAfter      map[int]time.Time
+	loadedAt        time.Time
+
+	// loads - перечитывание кэша: одновременно идёт не больше одного
+	loads singleflight.Group
+}
+
+func NewRevocationService(repo *repository.TokenRevocationRepository, sessionRepo *repository.SessionRepository, accessTTL time.Duration) *RevocationService {
+	return &RevocationService{
+		repo:            repo,
+		sessionRepo:     sessionRepo,
+		accessTTL:       accessTTL,
+		revoked:         make(map[string]time.Time),
+		revokedSessions: make(map[string]time.Time),
+		validAfter:      make(map[int]time.Time),
+	}
+}
+
+// IsRevoked реализует middleware.RevocationChecker
+func (s *RevocationService) IsRevoked(tokenID, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	if err := s.refresh(); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.revoked[tokenID]; ok && tokenID != "" {
+		return true, nil
+	}
+	if _, ok := s.revokedSessions[sessionID]; ok && sessionID != "" {
+		return true, nil
+	}
+	// iat и tokens_valid_after - с точностью до секунды. Токены, выданные в секунду отзыва,
+	// различает сессия: сессии, начатые до отзыва, завершены вместе с ним, а новая сессия
+	// (например, после смены пароля) - нет. Токены без сессии из этой секунды отзываются.
+	if validAfter, ok := s.validAfter[userID]; ok &&
+		(issuedAt.Before(validAfter) || issuedAt.Equal(validAfter) && sessionID == "") {
+		return true, nil
+	}
+	return false, nil
+}
+
+// SessionsRevoked применяет отзыв сессий к кэшу сразу, не дожидаясь его обновления.
+// Сами сессии отзываются в БД вызывающим кодом.
+func (s *RevocationService) SessionsRevoked(sessionIDs ...string) {
+	now := time.Now()
+	s.mu.Lock()
+	for _, id := range sessionIDs {
+		s.revokedSessions[id] = now
+	}
+	s.mu.Unlock()
+}
+
+// RevokeToken отзывает один access токен до истечения его срока
+func (s *RevocationService) RevokeToken(tokenID string, userID int, expiresAt time.Time) error {
+	if tokenID == "" {
+		return nil
+	}
+	if err := s.repo.Revoke(tokenID, userID, expiresAt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.revoked[tokenID] = expiresAt
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeAllForUser отзывает все access токены пользователя, выданные до этого момента.
+// Токены из текущей секунды, у которых есть сессия, отзывает завершение их сессий.
+func (s *RevocationService) RevokeAllForUser(userID int) error {
+	validAfter := time.Now().Truncate(time.Second)
+	if err := s.repo.InvalidateUserTokens(userID, validAfter); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.validAfter[userID] = validAfter
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *RevocationService) DeleteExpired() (int64, error) {
+	return s.repo.DeleteExpired()
+}
+
+// refresh перечитывает кэш, если он устарел. Запросы, пришедшие во время
+// перечитывания, ждут его результата. Если БД недоступна, а кэш уже
+// загружался, используется старый снимок.
+func (s *RevocationService) refresh() error {
+	if s.fresh() {
+		return nil
+	}
+
+	_, err, _ := s.loads.Do("load", func() (any, error) {
+		// Кэш мог обновить только что завершившийся вызов
+		if s.fresh() {
+			return nil, nil
+		}
+		return nil, s.reload()
+	})
+	return err
+}
+
+func (s *RevocationService) fresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.loadedAt) < revocationCacheTTL
+}
+
+func (s *RevocationService) reload() error {
+	s.mu.RLock()
+	loaded := !s.loadedAt.IsZero()
+	s.mu.RUnlock()
+
+	revoked, validAfter, revokedSessions, err := s.load()
+	if err == nil {
+		s.mu.Lock()
+		s.merge(revoked, validAfter, revokedSessions)
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+		return nil
+	}
+
+	if !loaded {
+		return err
+	}
+
+	s.mu.Lock()
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	log.Printf("Failed to refresh token revocation cache, using previous snapshot: %v", err)
+	return nil
+}
+
+// merge заменяет кэш снимком из БД, сохраняя в нём отзывы этого процесса: отзыв,
+// сделанный во время чтения снимка, в снимок мог не попасть. Истёкшие записи
+// отбрасываются. Вызывается под mu.
+func (s *RevocationService) merge(revoked map[string]time.Time, validAfter map[int]time.Time, revokedSessions map[string]time.Time) {
+	now := time.Now()
+	since := now.Add(-s.accessTTL)
+
+	for id, expiresAt := range s.revoked {
+		if _, ok := revoked[id]; !ok && expiresAt.After(now) {
+			revoked[id] = expiresAt
+		}
+	}
+	for userID, at := range s.validAfter {
+		if at.After(since) && at.After(validAfter[userID]) {
+			validAfter[userID] = at
+		}
+	}
+	for id, revokedAt := range s.revokedSessions {
+		if _, ok := revokedSessions[id]; !ok && revokedAt.After(since) {
+			revokedSessions[id] = revokedAt
+		}
+	}
+
+	s.revoked = revoked
+	s.validAfter = validAfter
+	s.revokedSessions = revokedSessions
+}
+
+func (s *RevocationService) load() (map[string]time.Time, map[int]time.Time, map[string]time.Time, error) {
+	revoked, err := s.repo.GetRevoked()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Токены старше времени жизни access токена истекли сами
+	since := time.Now().Add(-s.accessTTL)
+	validAfter, err := s.repo.GetTokensValidAfter(since)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	revokedSessions, err := s.sessionRepo.GetRevokedSince(since)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return revoked, validAfter, revokedSessions, nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevocationMergeKeepsLocalRevocations(t *testing.T) {
+	now := time.Now()
+	s := NewRevocationService(nil, nil, 15*time.Minute)
+
+	// Отзывы этого процесса, сделанные, пока читался снимок
+	s.revoked["local-token"] = now.Add(time.Minute)
+	s.revoked["expired-token"] = now.Add(-time.Minute)
+	s.revokedSessions["local-session"] = now
+	s.revokedSessions["old-session"] = now.Add(-time.Hour)
+	s.validAfter[1] = now
+	s.validAfter[2] = now.Add(-time.Hour)
+	s.validAfter[3] = now.Add(-time.Minute)
+
+	s.merge(
+		map[string]time.Time{"db-token": now.Add(time.Minute)},
+		map[int]time.Time{3: now},
+		map[string]time.Time{"db-session": now},
+	)
+
+	for _, id := range []string{"local-token", "db-token"} {
+		if _, ok := s.revoked[id]; !ok {
+			t.Errorf("token %s is not revoked after merge", id)
+		}
+	}
+	if _, ok := s.revoked["expired-token"]; ok {
+		t.Error("expired token was kept")
+	}
+	for _, id := range []string{"local-session", "db-session"} {
+		if _, ok := s.revokedSessions[id]; !ok {
+			t.Errorf("session %s is not revoked after merge", id)
+		}
+	}
+	if _, ok := s.revokedSessions["old-session"]; ok {
+		t.Error("session revoked before the access token lifetime was kept")
+	}
+
+	if !s.validAfter[1].Equal(now) {
+		t.Errorf("local revoke-all for user 1 was lost: %v", s.validAfter[1])
+	}
+	if _, ok := s.validAfter[2]; ok {
+		t.Error("revoke-all older than the access token lifetime was kept")
+	}
+	// Из снимка и локального отзыва берётся более поздний
+	if !s.validAfter[3].Equal(now) {
+		t.Errorf("valid after for user 3 = %v, want %v", s.validAfter[3], now)
+	}
+}
+
+func TestRevokeAllInTheSameSecond(t *testing.T) {
+	s := NewRevocationService(nil, nil, 15*time.Minute)
+	s.loadedAt = time.Now()
+
+	revokedAt := time.Now().Truncate(time.Second)
+	s.validAfter[1] = revokedAt
+	s.revokedSessions["old-session"] = revokedAt
+
+	tests := []struct {
+		name      string
+		sessionID string
+		issuedAt  time.Time
+		revoked   bool
+	}{
+		{"issued a second earlier", "new-session", revokedAt.Add(-time.Second), true},
+		{"same second, revoked session", "old-session", revokedAt, true},
+		{"same second, no session", "", revokedAt, true},
+		{"same second, session started after revoke", "new-session", revokedAt, false},
+		{"next second", "", revokedAt.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := s.IsRevoked("token", tt.sessionID, 1, tt.issuedAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.revoked {
+				t.Fatalf("IsRevoked = %v, want %v", revoked, tt.revoked)
+			}
+		})
+	}
+}
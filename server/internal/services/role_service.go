@@ -0,0 +1,91 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrRoleNotGranted      = errors.New("user does not have this role")
+	ErrCannotRevokeOwnRole = errors.New("you cannot revoke your own admin role")
+)
+
+type RoleService struct {
+	roleRepo  *repository.RoleRepository
+	userRepo  *repository.UserRepository
+	tokens    *TokenService
+	validator *validator.Validate
+}
+
+func NewRoleService(roleRepo *repository.RoleRepository, userRepo *repository.UserRepository, tokens *TokenService) *RoleService {
+	return &RoleService{
+		roleRepo:  roleRepo,
+		userRepo:  userRepo,
+		tokens:    tokens,
+		validator: newValidator(),
+	}
+}
+
+func (s *RoleService) GetUserRoles(userID int) ([]models.UserRole, error) {
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.GetUserRoles(userID)
+}
+
+// GrantRole назначает роль. Роли попадают в access токен и перечитываются при его обновлении,
+// поэтому новая роль действует не позже, чем истечёт текущий access токен.
+func (s *RoleService) GrantRole(actorID, userID int, req *models.GrantRoleRequest) ([]models.UserRole, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.Grant(userID, req.Role, &actorID); err != nil {
+		return nil, err
+	}
+
+	return s.roleRepo.GetUserRoles(userID)
+}
+
+// RevokeRole снимает роль и завершает все входы пользователя: иначе выданные access токены
+// сохраняли бы права роли до истечения срока.
+func (s *RoleService) RevokeRole(actorID, userID int, role string) ([]models.UserRole, error) {
+	// Администратор не может случайно лишить себя доступа к управлению ролями
+	if actorID == userID && role == models.RoleAdmin {
+		return nil, ErrCannotRevokeOwnRole
+	}
+	if err := s.checkUser(userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.Revoke(userID, role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoleNotGranted
+		}
+		return nil, err
+	}
+	if err := s.tokens.RevokeAll(userID); err != nil {
+		return nil, err
+	}
+
+	return s.roleRepo.GetUserRoles(userID)
+}
+
+func (s *RoleService) checkUser(userID int) error {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
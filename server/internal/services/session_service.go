@@ -0,0 +1,115 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrCurrentSessionUnknown = errors.New("current session unknown")
+)
+
+// SessionService ведёт список входов пользователя с разных устройств.
+// Отзыв сессии отзывает её refresh токены и сразу закрывает выданные ей access токены.
+type SessionService struct {
+	sessionRepo      *repository.SessionRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
+	revocations      *RevocationService
+	accessTTL        time.Duration
+}
+
+func NewSessionService(sessionRepo *repository.SessionRepository, refreshTokenRepo *repository.RefreshTokenRepository, revocations *RevocationService, accessTTL time.Duration) *SessionService {
+	return &SessionService{
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocations:      revocations,
+		accessTTL:        accessTTL,
+	}
+}
+
+// List возвращает активные сессии пользователя, отмечая ту, которой выполнен запрос
+func (s *SessionService) List(userID int, currentID string) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.GetActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// Revoke завершает одну сессию пользователя
+func (s *SessionService) Revoke(userID int, id string) error {
+	if err := s.sessionRepo.Revoke(userID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return s.revoked(id)
+}
+
+// RevokeOthers завершает все сессии пользователя, кроме текущей. Без текущей сессии
+// (токен выдан до появления сессий) завершились бы все, поэтому запрос отклоняется.
+func (s *SessionService) RevokeOthers(userID int, currentID string) (int, error) {
+	if currentID == "" {
+		return 0, ErrCurrentSessionUnknown
+	}
+
+	ids, err := s.sessionRepo.RevokeAllExcept(userID, currentID)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), s.revoked(ids...)
+}
+
+func (s *SessionService) create(tx *sqlx.Tx, session *models.Session) error {
+	return s.sessionRepo.Create(tx, session)
+}
+
+func (s *SessionService) touch(tx *sqlx.Tx, id string, client models.ClientInfo, expiresAt time.Time) error {
+	return s.sessionRepo.Touch(tx, id, client, expiresAt)
+}
+
+// revokeByRefreshToken завершает сессию, к которой относится refresh токен
+func (s *SessionService) revokeByRefreshToken(tokenHash string) error {
+	ids, err := s.sessionRepo.RevokeByRefreshTokenHash(tokenHash)
+	if err != nil {
+		return err
+	}
+	return s.revoked(ids...)
+}
+
+// revokeAll отмечает все сессии пользователя завершёнными
+func (s *SessionService) revokeAll(userID int) error {
+	ids, err := s.sessionRepo.RevokeAllExcept(userID, "")
+	if err != nil {
+		return err
+	}
+	return s.revoked(ids...)
+}
+
+// revoked отзывает refresh токены завершённых сессий и применяет отзыв к кэшу проверки access токенов
+func (s *SessionService) revoked(ids ...string) error {
+	for _, id := range ids {
+		if err := s.refreshTokenRepo.RevokeFamily(id); err != nil {
+			return err
+		}
+	}
+	s.revocations.SessionsRevoked(ids...)
+	return nil
+}
+
+// deleteStale удаляет истёкшие сессии и отозванные сессии, у которых не осталось живых access токенов
+func (s *SessionService) deleteStale() (int64, error) {
+	return s.sessionRepo.DeleteStale(time.Now().Add(-s.accessTTL))
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/middleware"
+	"tenderness/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, all sessions of this login were revoked")
+)
+
+// TokenService выдаёт пары access + refresh токенов и ротирует refresh токены.
+// Refresh токен одноразовый: при обмене выдаётся новый токен того же семейства.
+// Повторное предъявление использованного токена означает его утечку, поэтому
+// отзывается всё семейство.
+type TokenService struct {
+	tx               *repository.TxManager
+	refreshTokenRepo *repository.RefreshTokenRepository
+	userRepo         *repository.UserRepository
+	revocations      *RevocationService
+	sessions         *SessionService
+	jwt              *middleware.JWTMiddleware
+	refreshTTL       time.Duration
+}
+
+func NewTokenService(tx *repository.TxManager, refreshTokenRepo *repository.RefreshTokenRepository, userRepo *repository.UserRepository, revocations *RevocationService, sessions *SessionService, jwt *middleware.JWTMiddleware, refreshTTL time.Duration) *TokenService {
+	return &TokenService{
+		tx:               tx,
+		refreshTokenRepo: refreshTokenRepo,
+		userRepo:         userRepo,
+		revocations:      revocations,
+		sessions:         sessions,
+		jwt:              jwt,
+		refreshTTL:       refreshTTL,
+	}
+}
+
+// NewAuthResponse выдаёт пользователю новую пару токенов, начиная новое семейство
+// и новую сессию для устройства client
+func (s *TokenService) NewAuthResponse(user models.UserResponse, client models.ClientInfo) (*models.AuthResponse, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	var refreshToken string
+	err = s.tx.WithTx(func(tx *sqlx.Tx) error {
+		err := s.sessions.create(tx, &models.Session{
+			ID:        familyID,
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(s.refreshTTL),
+			UserAgent: client.UserAgent,
+			IP:        client.IP,
+		})
+		if err != nil {
+			return err
+		}
+
+		refreshToken, err = s.issueRefreshToken(tx, user.ID, familyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildResponse(user, refreshToken, familyID)
+}
+
+// Refresh обменивает refresh токен на новую пару токенов
+func (s *TokenService) Refresh(rawToken string, client models.ClientInfo) (*models.AuthResponse, error) {
+	if rawToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var (
+		stored       *models.RefreshToken
+		refreshToken string
+	)
+	err := s.tx.WithTx(func(tx *sqlx.Tx) error {
+		var err error
+		stored, err = s.refreshTokenRepo.GetByHashForUpdate(tx, hashToken(rawToken))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if stored.UsedAt != nil {
+			return ErrRefreshTokenReused
+		}
+		if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := s.refreshTokenRepo.MarkUsed(tx, stored.ID); err != nil {
+			return err
+		}
+		if err := s.sessions.touch(tx, stored.FamilyID, client, time.Now().Add(s.refreshTTL)); err != nil {
+			return err
+		}
+
+		refreshToken, err = s.issueRefreshToken(tx, stored.UserID, stored.FamilyID)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		// Отзыв выполняется вне откатившейся транзакции, чтобы он сохранился
+		log.Printf("Refresh token reuse detected for user %d, revoking token family", stored.UserID)
+		if revokeErr := s.sessions.Revoke(stored.UserID, stored.FamilyID); revokeErr != nil && !errors.Is(revokeErr, ErrSessionNotFound) {
+			return nil, revokeErr
+		}
+		// Семейства, выданные до появления сессий, отзываются напрямую
+		if revokeErr := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); revokeErr != nil {
+			return nil, revokeErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadUser(stored.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Пользователь удалил аккаунт
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	return s.buildResponse(*user, refreshToken, stored.FamilyID)
+}
+
+// NewAuthResponseForUser загружает профиль пользователя и выдаёт ему новую пару токенов
+func (s *TokenService) NewAuthResponseForUser(userID int, client models.ClientInfo) (*models.AuthResponse, error) {
+	user, err := s.loadUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.NewAuthResponse(*user, client)
+}
+
+func (s *TokenService) loadUser(userID int) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.userRepo.GetRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Phone:         user.Phone,
+		IsActive:      user.IsActive,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Roles:         roles,
+		CreatedAt:     user.CreatedAt,
+		AvatarURL:     user.AvatarURL,
+		AuthProvider:  user.AuthProvider,
+	}, nil
+}
+
+// Logout завершает текущую сессию: отзывает access токен и refresh токены того же входа
+func (s *TokenService) Logout(claims *middleware.JWTClaims, refreshToken string) error {
+	if claims != nil && claims.ExpiresAt != nil {
+		if err := s.revocations.RevokeToken(claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+	}
+	if claims != nil && claims.SessionID != "" {
+		if err := s.sessions.Revoke(claims.UserID, claims.SessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	if refreshToken != "" {
+		if err := s.sessions.revokeByRefreshToken(hashToken(refreshToken)); err != nil {
+			return err
+		}
+		return s.refreshTokenRepo.RevokeFamilyByTokenHash(hashToken(refreshToken))
+	}
+	return nil
+}
+
+// RevokeAll завершает все входы пользователя: отзывает выданные access и refresh токены.
+// Вызывается при выходе со всех устройств, смене пароля и удалении аккаунта.
+// Сессии завершаются первыми: они отзывают и токены, выданные в секунду отзыва.
+func (s *TokenService) RevokeAll(userID int) error {
+	if err := s.sessions.revokeAll(userID); err != nil {
+		return err
+	}
+	if err := s.revocations.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	return s.refreshTokenRepo.RevokeAllForUser(userID)
+}
+
+// DeleteExpired удаляет истёкшие refresh токены, сессии и записи об отзыве истёкших access токенов
+func (s *TokenService) DeleteExpired() error {
+	if _, err := s.refreshTokenRepo.DeleteExpired(); err != nil {
+		return err
+	}
+	if _, err := s.sessions.deleteStale(); err != nil {
+		return err
+	}
+	_, err := s.revocations.DeleteExpired()
+	return err
+}
+
+func (s *TokenService) issueRefreshToken(tx *sqlx.Tx, userID int, familyID string) (string, error) {
+	rawToken, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.refreshTokenRepo.Create(tx, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(rawToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+func (s *TokenService) buildResponse(user models.UserResponse, refreshToken, sessionID string) (*models.AuthResponse, error) {
+	accessToken, err := s.jwt.GenerateToken(user.ID, user.Email, user.Roles, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		User:             user,
+		Token:            accessToken,
+		ExpiresIn:        int(s.jwt.AccessTTL().Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int(s.refreshTTL.Seconds()),
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
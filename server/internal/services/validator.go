@@ -0,0 +1,22 @@
+package services
+
+import (
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// newValidator создаёт валидатор, который в ошибках использует JSON имена вместо имен полей структуры
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := field.Tag.Get("json")
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	return v
+}
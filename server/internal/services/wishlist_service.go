@@ -0,0 +1,115 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+
+	"tenderness/internal/domain/models"
+	"tenderness/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrWishlistItemNotFound = errors.New("wishlist item not found")
+
+type WishlistService struct {
+	wishlistRepo *repository.WishlistRepository
+	productRepo  *repository.ProductRepository
+	cartService  *CartService
+	validator    *validator.Validate
+}
+
+func NewWishlistService(wishlistRepo *repository.WishlistRepository, productRepo *repository.ProductRepository, cartService *CartService) *WishlistService {
+	return &WishlistService{
+		wishlistRepo: wishlistRepo,
+		productRepo:  productRepo,
+		cartService:  cartService,
+		validator:    newValidator(),
+	}
+}
+
+func (s *WishlistService) GetWishlist(userID int) ([]models.WishlistLine, error) {
+	return s.wishlistRepo.GetLines(userID)
+}
+
+// AddItem сохраняет товар в избранное. В отличие от корзины, товар может
+// отсутствовать в наличии, а вариант можно не выбирать.
+func (s *WishlistService) AddItem(userID int, req *models.AddWishlistItemRequest) ([]models.WishlistLine, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.productRepo.GetByID(req.ProductID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	if req.VariantID != nil {
+		if _, err := s.productRepo.GetVariant(req.ProductID, *req.VariantID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrVariantNotFound
+			}
+			return nil, err
+		}
+	}
+
+	if err := s.wishlistRepo.Add(userID, req.ProductID, req.VariantID); err != nil {
+		return nil, err
+	}
+
+	return s.wishlistRepo.GetLines(userID)
+}
+
+func (s *WishlistService) RemoveItem(userID, itemID int) ([]models.WishlistLine, error) {
+	if err := s.wishlistRepo.Remove(userID, itemID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWishlistItemNotFound
+		}
+		return nil, err
+	}
+
+	return s.wishlistRepo.GetLines(userID)
+}
+
+// MoveToCart добавляет товар из избранного в корзину пользователя и убирает его из избранного.
+// Если товар нельзя купить (нет в наличии, не выбран вариант), позиция остаётся в избранном.
+func (s *WishlistService) MoveToCart(userID, itemID int, req *models.MoveWishlistItemRequest) (*models.CartResponse, error) {
+	if err := s.validator.Struct(req); err != nil {
+		return nil, err
+	}
+
+	item, err := s.wishlistRepo.GetItem(userID, itemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWishlistItemNotFound
+		}
+		return nil, err
+	}
+
+	variantID := item.VariantID
+	if variantID == nil {
+		variantID = req.VariantID
+	}
+
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	cart, err := s.cartService.AddItem(models.CartOwner{UserID: userID}, &models.AddCartItemRequest{
+		ProductID: item.ProductID,
+		VariantID: variantID,
+		Quantity:  quantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.wishlistRepo.Remove(userID, item.ID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return cart, nil
+}
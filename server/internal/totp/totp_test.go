@@ -0,0 +1,107 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret - ключ "12345678901234567890" из приложения B RFC 6238 в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// Тестовые векторы RFC 6238 для HMAC-SHA1; в RFC коды из 8 цифр, здесь их последние 6
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	for _, v := range rfcVectors {
+		code, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("Code at %d = %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestValidateRFC6238Vectors(t *testing.T) {
+	for _, v := range rfcVectors {
+		at := time.Unix(v.unix, 0)
+		step, ok := Validate(rfcSecret, v.code, at, 0)
+		if !ok || step != Step(at) {
+			t.Errorf("Validate(%s) at %d = %d, %v; want %d, true", v.code, v.unix, step, ok, Step(at))
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	codeAt := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name string
+		step int64
+		skew int
+		ok   bool
+	}{
+		{"current step without skew", current, 0, true},
+		{"previous step without skew", current - 1, 0, false},
+		{"previous step within skew", current - 1, 1, true},
+		{"next step within skew", current + 1, 1, true},
+		{"two steps back outside skew", current - 2, 1, false},
+		{"two steps ahead outside skew", current + 2, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, codeAt(tt.step), now, tt.skew)
+			if ok != tt.ok {
+				t.Fatalf("Validate ok = %v, want %v", ok, tt.ok)
+			}
+			// Возвращается шаг кода, а не текущий, чтобы код нельзя было использовать повторно
+			if ok && step != tt.step {
+				t.Fatalf("Validate step = %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+func TestValidateNormalizesInput(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	if _, ok := Validate(rfcSecret, " 287 082 ", at, 0); !ok {
+		t.Error("code with spaces was rejected")
+	}
+	if _, ok := Validate(rfcSecret, "94287082", at, 0); ok {
+		t.Error("8 digit code was accepted")
+	}
+	if _, ok := Validate(rfcSecret, "", at, 0); ok {
+		t.Error("empty code was accepted")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Fatalf("generated secret cannot be decoded: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products ADD COLUMN stock_quantity INTEGER NOT NULL DEFAULT 0 CHECK (stock_quantity >= 0);
+ALTER TABLE products ADD COLUMN reserved_quantity INTEGER NOT NULL DEFAULT 0 CHECK (reserved_quantity >= 0);
+ALTER TABLE products ADD CONSTRAINT products_reserved_within_stock CHECK (reserved_quantity <= stock_quantity);
+
+-- Реальные остатки неизвестны: все товары начинают с нулевого остатка, пока сотрудники
+-- не введут количества через админку. Иначе заказы принимались бы под несуществующий товар.
+UPDATE products SET stock_quantity = 0;
+
+-- in_stock теперь вычисляется из остатка
+DROP INDEX IF EXISTS idx_products_in_stock;
+ALTER TABLE products DROP COLUMN in_stock;
+ALTER TABLE products ADD COLUMN in_stock BOOLEAN GENERATED ALWAYS AS (stock_quantity > reserved_quantity) STORED;
+CREATE INDEX IF NOT EXISTS idx_products_in_stock ON products(in_stock);
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'committed', 'released', 'restocked')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_order_id ON stock_reservations(order_id);
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active_expires_at ON stock_reservations(expires_at) WHERE status = 'active';
+
+CREATE TRIGGER update_stock_reservations_updated_at
+    BEFORE UPDATE ON stock_reservations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_stock_reservations_updated_at ON stock_reservations;
+DROP INDEX IF EXISTS idx_stock_reservations_active_expires_at;
+DROP INDEX IF EXISTS idx_stock_reservations_order_id;
+DROP TABLE IF EXISTS stock_reservations;
+
+DROP INDEX IF EXISTS idx_products_in_stock;
+ALTER TABLE products DROP COLUMN in_stock;
+ALTER TABLE products ADD COLUMN in_stock BOOLEAN DEFAULT true;
+UPDATE products SET in_stock = stock_quantity > reserved_quantity;
+CREATE INDEX IF NOT EXISTS idx_products_in_stock ON products(in_stock);
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_reserved_within_stock;
+ALTER TABLE products DROP COLUMN reserved_quantity;
+ALTER TABLE products DROP COLUMN stock_quantity;
+-- +goose StatementEnd
@@ -0,0 +1,84 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    size VARCHAR(20) NOT NULL DEFAULT '',
+    color VARCHAR(50) NOT NULL DEFAULT '',
+    material VARCHAR(100) NOT NULL DEFAULT '',
+    price DECIMAL(10, 2),
+    stock_quantity INTEGER NOT NULL DEFAULT 0 CHECK (stock_quantity >= 0),
+    reserved_quantity INTEGER NOT NULL DEFAULT 0 CHECK (reserved_quantity >= 0),
+    in_stock BOOLEAN GENERATED ALWAYS AS (stock_quantity > reserved_quantity) STORED,
+    image_urls TEXT[] NOT NULL DEFAULT '{}',
+    CONSTRAINT product_variants_reserved_within_stock CHECK (reserved_quantity <= stock_quantity),
+    UNIQUE (product_id, size, color, material)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+
+CREATE TRIGGER update_product_variants_updated_at
+    BEFORE UPDATE ON product_variants
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Остаток товара с вариантами равен сумме остатков его вариантов
+CREATE OR REPLACE FUNCTION sync_product_stock_from_variants()
+RETURNS TRIGGER AS $$
+DECLARE
+    target_product_id INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        target_product_id = OLD.product_id;
+    ELSE
+        target_product_id = NEW.product_id;
+    END IF;
+
+    UPDATE products SET
+        stock_quantity = COALESCE((SELECT SUM(stock_quantity) FROM product_variants WHERE product_id = target_product_id), 0),
+        reserved_quantity = COALESCE((SELECT SUM(reserved_quantity) FROM product_variants WHERE product_id = target_product_id), 0)
+    WHERE id = target_product_id;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER sync_product_stock_on_variant_change
+    AFTER INSERT OR UPDATE OF stock_quantity, reserved_quantity OR DELETE ON product_variants
+    FOR EACH ROW
+    EXECUTE FUNCTION sync_product_stock_from_variants();
+
+-- Корзины, заказы и резервы ссылаются на варианты
+ALTER TABLE cart_items ADD COLUMN variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE;
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS cart_items_cart_id_product_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cart_items_unique_line ON cart_items(cart_id, product_id, (COALESCE(variant_id, 0)));
+
+ALTER TABLE order_items ADD COLUMN variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL;
+ALTER TABLE order_items ADD COLUMN sku VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE order_items ADD COLUMN variant_name VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE stock_reservations ADD COLUMN variant_id INTEGER REFERENCES product_variants(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE stock_reservations DROP COLUMN variant_id;
+
+ALTER TABLE order_items DROP COLUMN variant_name;
+ALTER TABLE order_items DROP COLUMN sku;
+ALTER TABLE order_items DROP COLUMN variant_id;
+
+DELETE FROM cart_items WHERE variant_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_cart_items_unique_line;
+ALTER TABLE cart_items DROP COLUMN variant_id;
+ALTER TABLE cart_items ADD CONSTRAINT cart_items_cart_id_product_id_key UNIQUE (cart_id, product_id);
+
+DROP TRIGGER IF EXISTS sync_product_stock_on_variant_change ON product_variants;
+DROP FUNCTION IF EXISTS sync_product_stock_from_variants();
+DROP TRIGGER IF EXISTS update_product_variants_updated_at ON product_variants;
+DROP INDEX IF EXISTS idx_product_variants_product_id;
+DROP TABLE IF EXISTS product_variants;
+-- +goose StatementEnd
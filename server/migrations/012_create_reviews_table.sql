@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title VARCHAR(200) NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_product_id ON reviews(product_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews(user_id);
+
+CREATE TRIGGER update_reviews_updated_at
+    BEFORE UPDATE ON reviews
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE products ADD COLUMN review_count INTEGER NOT NULL DEFAULT 0;
+
+-- Рейтинг теперь считается только по отзывам, поэтому значения из примерных данных сбрасываются
+UPDATE products SET
+    rating = COALESCE((SELECT ROUND(AVG(reviews.rating)::numeric, 2) FROM reviews WHERE reviews.product_id = products.id), 0),
+    review_count = (SELECT COUNT(*) FROM reviews WHERE reviews.product_id = products.id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE products DROP COLUMN review_count;
+DROP TRIGGER IF EXISTS update_reviews_updated_at ON reviews;
+DROP INDEX IF EXISTS idx_reviews_user_id;
+DROP INDEX IF EXISTS idx_reviews_product_id;
+DROP TABLE IF EXISTS reviews;
+-- +goose StatementEnd
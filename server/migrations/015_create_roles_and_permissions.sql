@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    granted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    granted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('customer', 'Покупатель'),
+    ('support', 'Служба поддержки: работа с заказами'),
+    ('catalog_manager', 'Менеджер каталога: товары и категории'),
+    ('admin', 'Администратор: полный доступ');
+
+INSERT INTO permissions (name, description) VALUES
+    ('catalog.manage', 'Создание, изменение и скрытие товаров и категорий'),
+    ('orders.manage', 'Просмотр заказов и смена их статусов'),
+    ('roles.manage', 'Назначение и снятие ролей пользователей');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('support', 'orders.manage'),
+    ('catalog_manager', 'catalog.manage'),
+    ('admin', 'catalog.manage'),
+    ('admin', 'orders.manage'),
+    ('admin', 'roles.manage');
+
+-- Роль из users.role переносится в user_roles, каждый пользователь остаётся покупателем
+INSERT INTO user_roles (user_id, role) SELECT id, 'customer' FROM users;
+INSERT INTO user_roles (user_id, role) SELECT id, role FROM users WHERE role <> 'customer';
+
+ALTER TABLE users DROP COLUMN role;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'customer'
+    CHECK (role IN ('customer', 'admin'));
+UPDATE users SET role = 'admin' WHERE id IN (SELECT user_id FROM user_roles WHERE role = 'admin');
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Счётчики неудачных входов: ключ вида "email:<адрес>", "user:<id>" или "ip:<адрес>"
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts(last_failure_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_login_attempts_last_failure_at;
+DROP TABLE IF EXISTS login_attempts;
+-- +goose StatementEnd
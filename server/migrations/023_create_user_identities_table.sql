@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Аккаунты провайдеров входа, привязанные к пользователю. email, name и avatar_url -
+-- снимок профиля у провайдера, обновляется при каждом входе
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    -- постоянный идентификатор пользователя у провайдера (sub в OpenID Connect)
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    email_verified BOOLEAN NOT NULL DEFAULT false,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    linked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject),
+    -- у пользователя не больше одного аккаунта каждого провайдера
+    UNIQUE (user_id, provider)
+);
+
+INSERT INTO user_identities (user_id, provider, subject, email, avatar_url, linked_at)
+SELECT id, 'google', google_id, email, COALESCE(avatar_url, ''), created_at
+FROM users WHERE google_id IS NOT NULL AND google_id <> '';
+
+INSERT INTO user_identities (user_id, provider, subject, email, avatar_url, linked_at)
+SELECT id, 'github', github_id, email, COALESCE(avatar_url, ''), created_at
+FROM users WHERE github_id IS NOT NULL AND github_id <> '';
+
+DROP INDEX IF EXISTS idx_users_google_id;
+DROP INDEX IF EXISTS idx_users_github_id;
+ALTER TABLE users DROP COLUMN IF EXISTS google_id;
+ALTER TABLE users DROP COLUMN IF EXISTS github_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS google_id VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS github_id VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_users_google_id ON users(google_id);
+CREATE INDEX IF NOT EXISTS idx_users_github_id ON users(github_id);
+
+UPDATE users u SET google_id = i.subject
+FROM user_identities i WHERE i.user_id = u.id AND i.provider = 'google';
+
+UPDATE users u SET github_id = i.subject
+FROM user_identities i WHERE i.user_id = u.id AND i.provider = 'github';
+
+DROP TABLE IF EXISTS user_identities;
+-- +goose StatementEnd
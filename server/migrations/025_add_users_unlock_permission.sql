@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Снять блокировку входа может поддержка, не получая права управлять ролями
+INSERT INTO permissions (name, description) VALUES
+    ('users.unlock', 'Снятие блокировки входа после неудачных попыток');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('support', 'users.unlock'),
+    ('admin', 'users.unlock');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE name = 'users.unlock';
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- Данные для локальной разработки, не для production. Миграции их не применяют:
+--   psql "host=localhost user=tenderness password=tenderness123 dbname=tenderness_db" -f server/seeds/dev.sql
+
+-- Размеры и цвета для белья из тестового каталога; остаток товара триггер пересчитает из вариантов
+INSERT INTO product_variants (product_id, sku, size, color, stock_quantity)
+SELECT p.id, 'P' || p.id || '-' || s.size || '-' || c.code, s.size, c.color, 10
+FROM products p
+CROSS JOIN (VALUES ('S'), ('M'), ('L')) AS s(size)
+CROSS JOIN (VALUES ('BLK', 'черный'), ('RED', 'красный')) AS c(code, color)
+WHERE p.category = 'Белье' AND p.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+-- Остатки остальных товаров из тестового каталога
+UPDATE products SET stock_quantity = 100
+WHERE stock_quantity = 0 AND deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id);